@@ -1,52 +1,176 @@
 package main
 
 import (
-	"bytes"
-	"html/template"
-	"sort"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/bitrise-steplib/steps-generate-changelog/git"
+	"github.com/bitrise-steplib/steps-generate-changelog/issues"
 )
 
-const changelogTmplStr = `{{range .Commits}}* [{{firstChars .Hash 7}}] {{.Message}}
-{{end}}`
+type changelogConfig struct {
+	Format        string
+	Template      string
+	Sections      sectionConfig
+	IssuePatterns []issues.Pattern
+	IncludeBody   bool
+	// ListContributors adds the contributors of the release to the default templates.
+	ListContributors bool
+	// Repository is used for linking the commits, links are not generated if its provider is unknown.
+	Repository git.Repository
+}
+
+type changelog struct {
+	Commits      []git.Commit `json:"commits"`
+	Sections     []Section    `json:"sections"`
+	CurrentDate  time.Time    `json:"current_date"`
+	Date         time.Time    `json:"date"`
+	Tag          string       `json:"tag"`
+	PreviousTag  string       `json:"previous_tag"`
+	CompareRange string       `json:"compare_range"`
+	CommitCount  int          `json:"commit_count"`
 
-var tmplFuncMap = template.FuncMap{
-	"firstChars": func(str string, length int) string {
-		if len(str) < length {
-			return str
+	RepositoryURL string `json:"repository_url,omitempty"`
+	CompareURL    string `json:"compare_url,omitempty"`
+
+	Issues       []issues.Reference `json:"issues"`
+	PullRequests []git.PullRequest  `json:"pull_requests"`
+	Contributors []git.Person       `json:"contributors"`
+}
+
+func changelogTemplate(tmplStr, tmplPth string) (string, error) {
+	if tmplStr != "" && tmplPth != "" {
+		return "", fmt.Errorf("only one of changelog_template and changelog_template_path can be set")
+	}
+
+	if tmplPth != "" {
+		b, err := os.ReadFile(tmplPth)
+		if err != nil {
+			return "", fmt.Errorf("unable to read changelog template (%s): %s", tmplPth, err)
 		}
+		return string(b), nil
+	}
 
-		return str[0:length]
-	},
+	return tmplStr, nil
 }
 
-type changelog struct {
-	Commits     []git.Commit
-	CurrentDate time.Time
+// history is the template context of the changelog of every release.
+type history struct {
+	Releases    []changelog `json:"releases"`
+	CurrentDate time.Time   `json:"current_date"`
+}
+
+func newChangelog(r release, cfg changelogConfig, currentDate time.Time) changelog {
+	// the commits are listed in the order of git log, the release is not modified
+	commits := append([]git.Commit{}, r.Commits...)
+	for i := range commits {
+		commits[i].URL = cfg.Repository.CommitURL(commits[i].Hash)
+		if commits[i].PullRequest != nil {
+			commits[i].PullRequest.URL = cfg.Repository.PullRequestURL(commits[i].PullRequest.Number)
+		}
+	}
+	grouped := groupFixups(commits)
+
+	compareURL := ""
+	if r.PreviousTag != "" {
+		compareURL = cfg.Repository.CompareURL(r.compareRefs())
+	}
+
+	return changelog{
+		Commits:      grouped,
+		Sections:     changelogSections(grouped, cfg.Sections),
+		CurrentDate:  currentDate,
+		Date:         r.EndCommit.Date,
+		Tag:          r.Tag,
+		PreviousTag:  r.PreviousTag,
+		CompareRange: r.compareRange(),
+		CommitCount:  len(commits),
+		Issues:       releaseIssues(cfg.IssuePatterns, r),
+		PullRequests: releasePullRequests(r),
+		Contributors: releaseContributors(r),
+
+		RepositoryURL: cfg.Repository.URL,
+		CompareURL:    compareURL,
+	}
 }
 
-func changelogContent(commits []git.Commit) (string, error) {
-	sort.Slice(commits, func(i, j int) bool {
-		return commits[i].Date.After(commits[j].Date)
-	})
-	chlog := changelog{
-		Commits:     commits,
-		CurrentDate: time.Now(),
+// releaseIssues returns the de-duplicated issue references of the commit messages of the releases.
+func releaseIssues(patterns []issues.Pattern, releases ...release) []issues.Reference {
+	var texts []string
+	for _, r := range releases {
+		for _, commit := range r.Commits {
+			texts = append(texts, commit.Message, commit.Body)
+		}
 	}
+	return issues.Find(patterns, texts...)
+}
+
+// releasePullRequests returns the de-duplicated pull requests of the commits of the releases, newest first.
+func releasePullRequests(releases ...release) []git.PullRequest {
+	var pullRequests []git.PullRequest
+	seen := map[int]bool{}
+	for _, r := range releases {
+		for _, commit := range r.Commits {
+			if commit.PullRequest == nil || seen[commit.PullRequest.Number] {
+				continue
+			}
+			seen[commit.PullRequest.Number] = true
+			pullRequests = append(pullRequests, *commit.PullRequest)
+		}
+	}
+	return pullRequests
+}
+
+// fixup! <subject>, squash! <subject> and amend! <subject> commits are created by git commit --fixup
+var fixupPrefixRegexp = regexp.MustCompile(`^(?:(?:fixup|squash|amend)! )+`)
+
+// groupFixups moves the follow-up commits into the Fixups of the commit they follow up:
+// the fixup!, squash! and amend! commits into the commit with the referenced subject,
+// and the further commits of a pull request into its first commit.
+// The commits are ordered from the newest, the fixups from the oldest.
+func groupFixups(commits []git.Commit) []git.Commit {
+	var grouped []git.Commit
+	bySubject := map[string]int{}
+	byPullRequest := map[int]int{}
+	for i := len(commits) - 1; i >= 0; i-- {
+		commit := commits[i]
 
-	tmplStr := changelogTmplStr
-	tmpl := template.New("changelog_content").Funcs(tmplFuncMap)
-	tmpl, err := tmpl.Parse(tmplStr)
-	if err != nil {
-		return "", err
+		target, ok := -1, false
+		if prefix := fixupPrefixRegexp.FindString(commit.Message); prefix != "" {
+			target, ok = bySubject[strings.TrimPrefix(commit.Message, prefix)]
+		}
+		if !ok && commit.PullRequest != nil {
+			target, ok = byPullRequest[commit.PullRequest.Number]
+		}
+		if ok {
+			grouped[target].Fixups = append(grouped[target].Fixups, commit)
+			continue
+		}
+
+		bySubject[commit.Message] = len(grouped)
+		if commit.PullRequest != nil {
+			byPullRequest[commit.PullRequest.Number] = len(grouped)
+		}
+		grouped = append(grouped, commit)
 	}
 
-	var buff bytes.Buffer
-	if err := tmpl.Execute(&buff, chlog); err != nil {
-		return "", err
+	for i, j := 0, len(grouped)-1; i < j; i, j = i+1, j-1 {
+		grouped[i], grouped[j] = grouped[j], grouped[i]
 	}
+	return grouped
+}
+
+func changelogContent(r release, cfg changelogConfig) (string, error) {
+	return renderChangelog(newChangelog(r, cfg, time.Now()), cfg)
+}
 
-	return buff.String(), nil
+func historyContent(releases []release, cfg changelogConfig) (string, error) {
+	h := history{CurrentDate: time.Now()}
+	for _, r := range releases {
+		h.Releases = append(h.Releases, newChangelog(r, cfg, h.CurrentDate))
+	}
+	return renderHistory(h, cfg)
 }
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/git"
+	"github.com/bitrise-steplib/steps-generate-changelog/issues"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_changelogContent(t *testing.T) {
+	r := release{
+		Commits: []git.Commit{
+			{Hash: "2222222222", Message: "second", Author: "Bob", Date: time.Unix(2, 0), Tag: "1.1.0"},
+			{Hash: "1111111111", Message: "first", Author: "Alice", Date: time.Unix(1, 0)},
+		},
+		Tag:         "1.1.0",
+		PreviousTag: "1.0.0",
+	}
+
+	t.Run("default template", func(t *testing.T) {
+		content, err := changelogContent(r, changelogConfig{Format: markdownFormat})
+		require.NoError(t, err)
+		require.Equal(t, "* [2222222] second\n* [1111111] first\n", content)
+	})
+
+	t.Run("custom template", func(t *testing.T) {
+		tmpl := `{{.PreviousTag}} -> {{.Tag}} ({{.CompareRange}}, {{.CommitCount}} commits)
+{{range .Commits}}{{.Author}}{{if .Tag}} [{{.Tag}}]{{end}}
+{{end}}`
+		content, err := changelogContent(r, changelogConfig{Format: markdownFormat, Template: tmpl})
+		require.NoError(t, err)
+		require.Equal(t, "1.0.0 -> 1.1.0 (1.0.0..1.1.0, 2 commits)\nBob [1.1.0]\nAlice\n", content)
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			format   string
+			tmpl     string
+			wantLine string
+		}{
+			{name: "first line", format: markdownFormat, tmpl: "{{.Tag}\n{{.PreviousTag}}\n", wantLine: "at line 1:"},
+			{name: "later line", format: markdownFormat, tmpl: "{{.Tag}}\n{{.PreviousTag}}\n\n{{range .Commits}}{{.Hash}\n{{end}}\n", wantLine: "at line 4:"},
+			{name: "unknown function", format: htmlFormat, tmpl: "<ul>\n\n<li>{{unknown .Tag}}</li>\n</ul>\n", wantLine: "at line 3:"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := changelogContent(r, changelogConfig{Format: tt.format, Template: tt.tmpl})
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "invalid changelog template "+tt.wantLine)
+			})
+		}
+	})
+}
+
+func Test_changelogContent_gitLogOrder(t *testing.T) {
+	// the commits of a rebase share a few dates, they are listed in the order of git log
+	var commits []git.Commit
+	want := ""
+	for i := 61; i > 0; i-- {
+		commits = append(commits, git.Commit{Hash: fmt.Sprintf("%010d", i), Message: "commit " + strconv.Itoa(i), Date: time.Unix(int64(i%3), 0)})
+		want += "commit " + strconv.Itoa(i) + "\n"
+	}
+	r := release{Commits: commits}
+
+	content, err := changelogContent(r, changelogConfig{Format: textFormat, Template: "{{range .Commits}}{{.Message}}\n{{end}}"})
+	require.NoError(t, err)
+	require.Equal(t, want, content)
+	require.Equal(t, "0000000061", r.Commits[0].Hash)
+}
+
+func Test_changelogTemplate(t *testing.T) {
+	tmplPth := filepath.Join(t.TempDir(), "changelog.tmpl")
+	require.NoError(t, os.WriteFile(tmplPth, []byte("{{.Tag}}"), 0600))
+
+	tmpl, err := changelogTemplate("", "")
+	require.NoError(t, err)
+	require.Equal(t, "", tmpl)
+
+	tmpl, err = changelogTemplate("{{.CommitCount}}", "")
+	require.NoError(t, err)
+	require.Equal(t, "{{.CommitCount}}", tmpl)
+
+	tmpl, err = changelogTemplate("", tmplPth)
+	require.NoError(t, err)
+	require.Equal(t, "{{.Tag}}", tmpl)
+
+	_, err = changelogTemplate("{{.CommitCount}}", tmplPth)
+	require.Error(t, err)
+}
+
+func Test_changelogContent_sections(t *testing.T) {
+	r := release{
+		Commits: []git.Commit{
+			newConventionalCommit("1111111111", "chore: bump deps", 1),
+			newConventionalCommit("2222222222", "fix(ui): broken button", 2),
+			newConventionalCommit("3333333333", "feat: login screen", 3),
+			newConventionalCommit("4444444444", "feat(api)!: drop v1 endpoints", 4),
+			newConventionalCommit("5555555555", "Update README", 5),
+		},
+	}
+
+	sectionCfg, err := newSectionConfig([]string{"fix=Fixes"}, []string{"chore"})
+	require.NoError(t, err)
+
+	content, err := changelogContent(r, changelogConfig{Format: markdownFormat, Sections: sectionCfg})
+	require.NoError(t, err)
+	require.Equal(t, `### Breaking Changes
+* [4444444] **api:** drop v1 endpoints
+
+### Features
+* [3333333] login screen
+
+### Fixes
+* [2222222] **ui:** broken button
+
+### Other Changes
+* [5555555] Update README
+`, content)
+
+	sectionCfg, err = newSectionConfig(nil, []string{"chore", "other"})
+	require.NoError(t, err)
+
+	content, err = changelogContent(r, changelogConfig{Format: markdownFormat, Sections: sectionCfg})
+	require.NoError(t, err)
+	require.NotContains(t, content, "Update README")
+	require.Contains(t, content, "login screen")
+
+	content, err = changelogContent(release{Commits: r.Commits[4:]}, changelogConfig{Format: markdownFormat, Sections: sectionCfg})
+	require.NoError(t, err)
+	require.Empty(t, content)
+}
+
+func Test_changelogContent_formats(t *testing.T) {
+	r := release{
+		Commits: []git.Commit{
+			newConventionalCommit("1111111111", "fix(ui): <button> & 'quote' in snake_case", 1),
+		},
+		Tag: "1.0.0",
+	}
+
+	t.Run("markdown", func(t *testing.T) {
+		content, err := changelogContent(r, changelogConfig{Format: markdownFormat})
+		require.NoError(t, err)
+		require.Equal(t, "### Bug Fixes\n* [1111111] **ui:** \\<button\\> & 'quote' in snake\\_case\n", content)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		content, err := changelogContent(r, changelogConfig{Format: textFormat})
+		require.NoError(t, err)
+		require.Equal(t, "Bug Fixes:\n* [1111111] ui: <button> & 'quote' in snake_case\n", content)
+	})
+
+	t.Run("html", func(t *testing.T) {
+		content, err := changelogContent(r, changelogConfig{Format: htmlFormat})
+		require.NoError(t, err)
+		require.Contains(t, content, "<h3>Bug Fixes</h3>")
+		require.Contains(t, content, "<li><code>1111111</code> <strong>ui:</strong> &lt;button&gt; &amp; &#39;quote&#39; in snake_case</li>")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		content, err := changelogContent(r, changelogConfig{Format: jsonFormat})
+		require.NoError(t, err)
+
+		var chlog map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(content), &chlog))
+		require.Equal(t, "1.0.0", chlog["tag"])
+		require.Equal(t, float64(1), chlog["commit_count"])
+
+		sections := chlog["sections"].([]interface{})
+		require.Len(t, sections, 1)
+		section := sections[0].(map[string]interface{})
+		require.Equal(t, "fix", section["type"])
+		commit := section["commits"].([]interface{})[0].(map[string]interface{})
+		require.Equal(t, "1111111111", commit["hash"])
+		require.Equal(t, "ui", commit["scope"])
+		require.Equal(t, "<button> & 'quote' in snake_case", commit["description"])
+	})
+}
+
+func Test_changelogContent_issues(t *testing.T) {
+	issuePatterns, err := issues.ParsePatterns([]string{
+		`#(\d+) https://github.com/owner/repo/issues/{id}`,
+		`(?i)\bTOOL-\d+ https://jira.example.com/browse/{key}`,
+	})
+	require.NoError(t, err)
+
+	r := release{
+		Commits: []git.Commit{
+			{Hash: "2222222222", Message: "Tool-248 firebase (#7)", Body: "Refs: #8", Date: time.Unix(2, 0)},
+			{Hash: "1111111111", Message: "fix <crash> (#7)", Date: time.Unix(1, 0)},
+		},
+	}
+	cfg := changelogConfig{Format: markdownFormat, IssuePatterns: issuePatterns}
+
+	content, err := changelogContent(r, cfg)
+	require.NoError(t, err)
+	require.Equal(t, `* [2222222] [Tool-248](https://jira.example.com/browse/Tool-248) firebase ([#7](https://github.com/owner/repo/issues/7))
+* [1111111] fix \<crash\> ([#7](https://github.com/owner/repo/issues/7))
+`, content)
+
+	cfg.Format = htmlFormat
+	content, err = changelogContent(r, cfg)
+	require.NoError(t, err)
+	require.Contains(t, content, `<li><code>1111111</code> fix &lt;crash&gt; (<a href="https://github.com/owner/repo/issues/7">#7</a>)</li>`)
+
+	cfg.Format = textFormat
+	cfg.Template = `{{range .Issues}}{{.Key}} {{.URL}}
+{{end}}`
+	content, err = changelogContent(r, cfg)
+	require.NoError(t, err)
+	require.Equal(t, `Tool-248 https://jira.example.com/browse/Tool-248
+#7 https://github.com/owner/repo/issues/7
+#8 https://github.com/owner/repo/issues/8
+`, content)
+}
+
+func Test_changelogContent_links(t *testing.T) {
+	r := release{
+		Commits:     []git.Commit{{Hash: "1111111111", Message: "first", Date: time.Unix(1, 0)}},
+		Tag:         "1.1.0",
+		PreviousTag: "1.0.0",
+	}
+	cfg := changelogConfig{
+		Format:     markdownFormat,
+		Repository: git.Repository{Provider: git.GitHub, URL: "https://github.com/owner/repo"},
+	}
+
+	content, err := changelogContent(r, cfg)
+	require.NoError(t, err)
+	require.Equal(t, `* [1111111](https://github.com/owner/repo/commit/1111111111) first
+
+[Full diff](https://github.com/owner/repo/compare/1.0.0...1.1.0)
+`, content)
+
+	cfg.Format = htmlFormat
+	content, err = changelogContent(r, cfg)
+	require.NoError(t, err)
+	require.Contains(t, content, `<li><a href="https://github.com/owner/repo/commit/1111111111"><code>1111111</code></a> first</li>`)
+	require.Contains(t, content, `<p><a href="https://github.com/owner/repo/compare/1.0.0...1.1.0">Full diff</a></p>`)
+}
+
+func Test_changelogContent_body(t *testing.T) {
+	r := release{
+		Commits: []git.Commit{
+			{Hash: "2222222222", Message: "second", Body: "Details of the change.\n\nSecond paragraph.\n\nSigned-off-by: Bob <bob@example.com>", Date: time.Unix(2, 0)},
+			{Hash: "1111111111", Message: "first", Date: time.Unix(1, 0)},
+		},
+	}
+	content, err := changelogContent(r, changelogConfig{Format: markdownFormat, IncludeBody: true})
+	require.NoError(t, err)
+	require.Equal(t, `* [2222222] second
+
+  Details of the change.
+
+  Second paragraph.
+* [1111111] first
+`, content)
+
+	content, err = changelogContent(r, changelogConfig{Format: markdownFormat})
+	require.NoError(t, err)
+	require.Equal(t, "* [2222222] second\n* [1111111] first\n", content)
+}
+
+func Test_changelogContent_pullRequests(t *testing.T) {
+	r := release{
+		Commits: []git.Commit{
+			{Hash: "3333333333", Message: "Merge pull request #3 from octocat/fix", Date: time.Unix(3, 0),
+				PullRequest: &git.PullRequest{Number: 3, Title: "Fix <crash>", Branch: "octocat/fix"}},
+			{Hash: "2222222222", Message: "direct", Date: time.Unix(2, 0)},
+		},
+	}
+
+	content, err := changelogContent(r, changelogConfig{Format: markdownFormat})
+	require.NoError(t, err)
+	require.Equal(t, "* [3333333] Fix \\<crash\\> (#3)\n* [2222222] direct\n", content)
+
+	content, err = changelogContent(r, changelogConfig{Format: textFormat})
+	require.NoError(t, err)
+	require.Equal(t, "* [3333333] Fix <crash> (#3)\n* [2222222] direct\n", content)
+}
+
+func Test_changelogContent_squashMerges(t *testing.T) {
+	r := release{
+		Commits: []git.Commit{
+			{Hash: "3333333333", Message: "fixup! Add login screen (#142)", Date: time.Unix(3, 0),
+				PullRequest: &git.PullRequest{Number: 142, Title: "fixup! Add login screen"}},
+			{Hash: "2222222222", Message: "Tool-248 firebase (#7)", Date: time.Unix(2, 0),
+				PullRequest: &git.PullRequest{Number: 7, Title: "Tool-248 firebase"}},
+			{Hash: "1111111111", Message: "Add login screen (#142)", Date: time.Unix(1, 0),
+				PullRequest: &git.PullRequest{Number: 142, Title: "Add login screen"}},
+		},
+	}
+	cfg := changelogConfig{Format: markdownFormat, Repository: git.Repository{Provider: git.GitHub, URL: "https://github.com/o/r"}}
+
+	content, err := changelogContent(r, cfg)
+	require.NoError(t, err)
+	require.Equal(t, `* [2222222](https://github.com/o/r/commit/2222222222) Tool-248 firebase ([#7](https://github.com/o/r/pull/7))
+* [1111111](https://github.com/o/r/commit/1111111111) Add login screen ([#142](https://github.com/o/r/pull/142))
+`, content)
+
+	require.Equal(t, []int{142, 7}, pullRequestNumbers(releasePullRequests(r)))
+}
+
+func pullRequestNumbers(pullRequests []git.PullRequest) []int {
+	var numbers []int
+	for _, pr := range pullRequests {
+		numbers = append(numbers, pr.Number)
+	}
+	return numbers
+}
+
+func Test_groupFixups(t *testing.T) {
+	commits := []git.Commit{
+		{Hash: "5", Message: "fixup! fixup! Add login screen"},
+		{Hash: "4", Message: "squash! Unknown commit"},
+		{Hash: "3", Message: "Fix review comments (#12)", PullRequest: &git.PullRequest{Number: 12}},
+		{Hash: "2", Message: "Add logout (#12)", PullRequest: &git.PullRequest{Number: 12}},
+		{Hash: "1", Message: "Add login screen"},
+	}
+
+	grouped := groupFixups(commits)
+	require.Len(t, grouped, 3)
+	require.Equal(t, "4", grouped[0].Hash)
+	require.Empty(t, grouped[0].Fixups)
+	require.Equal(t, "2", grouped[1].Hash)
+	require.Equal(t, []string{"3"}, commitHashes(grouped[1].Fixups))
+	require.Equal(t, "1", grouped[2].Hash)
+	require.Equal(t, []string{"5"}, commitHashes(grouped[2].Fixups))
+}
+
+func commitHashes(commits []git.Commit) []string {
+	var hashes []string
+	for _, commit := range commits {
+		hashes = append(hashes, commit.Hash)
+	}
+	return hashes
+}
+
+func Test_historyContent(t *testing.T) {
+	date := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	releases := []release{
+		{Commits: []git.Commit{newConventionalCommit("3333333333", "feat: unreleased feature", 3)}},
+		{
+			Commits:   []git.Commit{newConventionalCommit("2222222222", "fix: bug", 2)},
+			Tag:       "1.1.0",
+			EndCommit: git.Commit{Date: date},
+		},
+		{
+			Commits:   []git.Commit{newConventionalCommit("1111111111", "Initial commit", 1)},
+			Tag:       "1.0.0",
+			EndCommit: git.Commit{Date: date},
+		},
+	}
+
+	content, err := historyContent(releases, changelogConfig{Format: markdownFormat})
+	require.NoError(t, err)
+	require.Equal(t, `# Changelog
+
+## [Unreleased]
+### Features
+* [3333333] unreleased feature
+
+## [1.1.0] - 2020-01-02
+### Bug Fixes
+* [2222222] bug
+
+## [1.0.0] - 2020-01-02
+* [1111111] Initial commit
+`, content)
+
+	content, err = historyContent(releases[1:], changelogConfig{Format: textFormat, Template: "{{.CommitCount}} commits\n"})
+	require.NoError(t, err)
+	require.Equal(t, "1.1.0 - 2020-01-02\n1 commits\n\n1.0.0 - 2020-01-02\n1 commits\n", content)
+}
+
+func Test_newSectionConfig(t *testing.T) {
+	_, err := newSectionConfig([]string{"feat"}, nil)
+	require.Error(t, err)
+
+	cfg, err := newSectionConfig([]string{"", " Feat = New Features "}, []string{"CI", ""})
+	require.NoError(t, err)
+	require.Equal(t, "New Features", cfg.title("feat"))
+	require.Equal(t, "Bug Fixes", cfg.title("fix"))
+	require.Equal(t, map[string]bool{"ci": true}, cfg.HiddenTypes)
+}
+
+func newConventionalCommit(hash, message string, date int64) git.Commit {
+	commit := git.Commit{Hash: hash, Message: message, Date: time.Unix(date, 0)}
+	if cc, ok := git.ParseConventionalCommit(message, ""); ok {
+		commit.ConventionalCommit = cc
+	}
+	return commit
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/git"
+)
+
+// releaseContributors returns the unique authors and co-authors of the commits of the release, ordered by name.
+// The contributors are identified by their email (or by their name if they have no email).
+func releaseContributors(r release) []git.Person {
+	var contributors []git.Person
+	seen := map[string]bool{}
+	for _, commit := range r.Commits {
+		for _, person := range commit.Contributors() {
+			key := strings.ToLower(person.Email)
+			if key == "" {
+				key = strings.ToLower(person.Name)
+			}
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			contributors = append(contributors, person)
+		}
+	}
+
+	sort.SliceStable(contributors, func(i, j int) bool {
+		return strings.ToLower(contributors[i].Name) < strings.ToLower(contributors[j].Name)
+	})
+	return contributors
+}
+
+// applyMailmap replaces the authors, committers and co-authors of the commits with their canonical name and email,
+// according to the .mailmap file of the repository.
+func applyMailmap(dir string, releases []release) error {
+	var people []git.Person
+	seen := map[git.Person]bool{}
+	collect := func(person git.Person) {
+		if !seen[person] {
+			seen[person] = true
+			people = append(people, person)
+		}
+	}
+	for _, r := range releases {
+		for _, commit := range r.Commits {
+			collect(git.Person{Name: commit.Author, Email: commit.AuthorEmail})
+			collect(git.Person{Name: commit.Committer, Email: commit.CommitterEmail})
+			for _, coAuthor := range commit.CoAuthors {
+				collect(coAuthor)
+			}
+		}
+	}
+
+	mapped, err := git.CheckMailmap(dir, people)
+	if err != nil {
+		return err
+	}
+	mailmap := map[git.Person]git.Person{}
+	for i, person := range people {
+		mailmap[person] = mapped[i]
+	}
+
+	for _, r := range releases {
+		for i := range r.Commits {
+			commit := &r.Commits[i]
+			author := mailmap[git.Person{Name: commit.Author, Email: commit.AuthorEmail}]
+			commit.Author, commit.AuthorEmail = author.Name, author.Email
+			committer := mailmap[git.Person{Name: commit.Committer, Email: commit.CommitterEmail}]
+			commit.Committer, commit.CommitterEmail = committer.Name, committer.Email
+
+			var coAuthors []git.Person
+			for _, coAuthor := range commit.CoAuthors {
+				coAuthors = append(coAuthors, mailmap[coAuthor])
+			}
+			commit.CoAuthors = coAuthors
+		}
+	}
+	return nil
+}
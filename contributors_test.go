@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/git"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_releaseContributors(t *testing.T) {
+	r := release{
+		Commits: []git.Commit{
+			{Author: "bob", AuthorEmail: "BOB@example.com"},
+			{Author: "Carol", AuthorEmail: "carol@example.com", CoAuthors: []git.Person{{Name: "Bob", Email: "bob@example.com"}, {Name: "Dave"}}},
+			{Author: "Alice", AuthorEmail: "alice@example.com", Committer: "GitHub", CommitterEmail: "noreply@github.com"},
+			{Author: "Dave"},
+		},
+	}
+
+	require.Equal(t, []git.Person{
+		{Name: "Alice", Email: "alice@example.com"},
+		{Name: "bob", Email: "BOB@example.com"},
+		{Name: "Carol", Email: "carol@example.com"},
+		{Name: "Dave"},
+	}, releaseContributors(r))
+}
+
+func Test_changelogContent_contributors(t *testing.T) {
+	r := release{
+		Commits: []git.Commit{
+			{Hash: "2222222222", Message: "second", Author: "Bob_B", AuthorEmail: "bob@example.com", Date: time.Unix(2, 0)},
+			{Hash: "1111111111", Message: "first", Author: "Alice", AuthorEmail: "alice@example.com", Date: time.Unix(1, 0)},
+		},
+	}
+
+	content, err := changelogContent(r, changelogConfig{Format: markdownFormat, ListContributors: true})
+	require.NoError(t, err)
+	require.Equal(t, `* [2222222] second
+* [1111111] first
+
+### Contributors
+* Alice
+* Bob\_B
+`, content)
+
+	content, err = changelogContent(r, changelogConfig{Format: textFormat, ListContributors: true})
+	require.NoError(t, err)
+	require.Equal(t, `* [2222222] second
+* [1111111] first
+
+Contributors:
+* Alice
+* Bob_B
+`, content)
+
+	content, err = changelogContent(r, changelogConfig{Format: markdownFormat})
+	require.NoError(t, err)
+	require.Equal(t, "* [2222222] second\n* [1111111] first\n", content)
+}
@@ -0,0 +1,254 @@
+package exporter
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/envman/envman"
+	"github.com/bitrise-io/go-steputils/v2/export"
+	"github.com/bitrise-io/go-utils/v2/command"
+	"github.com/bitrise-io/go-utils/v2/env"
+)
+
+// The names of the built-in backends.
+const (
+	EnvmanBackend        = "envman"
+	FileBackend          = "file"
+	StdoutBackend        = "stdout"
+	GitHubActionsBackend = "github-actions"
+	GitLabDotenvBackend  = "gitlab-dotenv"
+	DotenvBackend        = "dotenv"
+)
+
+// Backend exports the outputs of the step to a destination.
+type Backend interface {
+	// ExportOutput exports an output, without expanding env vars in the value.
+	ExportOutput(key, value string) error
+	// MaxEnvBytes returns the size limit of an output value, 0 if there is no limit.
+	MaxEnvBytes() (int, error)
+}
+
+// sizedBackend is implemented by the backends which write an output in a different size than its value,
+// like escaped or together with its key. Their MaxEnvBytes limits the size returned by EnvBytes.
+type sizedBackend interface {
+	EnvBytes(key, value string) int
+}
+
+// BackendConfig configures the backends.
+type BackendConfig struct {
+	// Stdout is written by the stdout backend.
+	Stdout io.Writer
+	// Getenv looks up the environment of the CI, like the GITHUB_OUTPUT file.
+	Getenv func(key string) string
+	// GitLabDotenvPath is the dotenv report artifact of the GitLab CI job.
+	GitLabDotenvPath string
+	// DotenvPath is the .env file written by the dotenv backend.
+	DotenvPath string
+}
+
+// BackendFactory creates a backend.
+type BackendFactory func(cfg BackendConfig) (Backend, error)
+
+var backends = map[string]BackendFactory{
+	EnvmanBackend: func(BackendConfig) (Backend, error) {
+		return envmanBackend{exporter: export.NewExporter(command.NewFactory(env.NewRepository()))}, nil
+	},
+	FileBackend: func(BackendConfig) (Backend, error) {
+		return fileBackend{}, nil
+	},
+	StdoutBackend: func(cfg BackendConfig) (Backend, error) {
+		return stdoutBackend{w: cfg.Stdout}, nil
+	},
+	GitHubActionsBackend: func(cfg BackendConfig) (Backend, error) {
+		pth := cfg.Getenv("GITHUB_OUTPUT")
+		if pth == "" {
+			return nil, fmt.Errorf("GITHUB_OUTPUT is not set, the %s backend can only be used in GitHub Actions", GitHubActionsBackend)
+		}
+		return githubActionsBackend{pth: pth}, nil
+	},
+	GitLabDotenvBackend: func(cfg BackendConfig) (Backend, error) {
+		if cfg.GitLabDotenvPath == "" {
+			return nil, fmt.Errorf("the dotenv report path of the %s backend is not set", GitLabDotenvBackend)
+		}
+		if err := truncateFile(cfg.GitLabDotenvPath); err != nil {
+			return nil, fmt.Errorf("unable to truncate the dotenv report (%s): %s", cfg.GitLabDotenvPath, err)
+		}
+		return gitlabDotenvBackend{pth: cfg.GitLabDotenvPath}, nil
+	},
+	DotenvBackend: func(cfg BackendConfig) (Backend, error) {
+		if cfg.DotenvPath == "" {
+			return nil, fmt.Errorf("the .env file path of the %s backend is not set", DotenvBackend)
+		}
+		if err := truncateFile(cfg.DotenvPath); err != nil {
+			return nil, fmt.Errorf("unable to truncate the .env file (%s): %s", cfg.DotenvPath, err)
+		}
+		return dotenvBackend{pth: cfg.DotenvPath}, nil
+	},
+}
+
+// RegisterBackend makes a backend selectable by its name, replacing the backend registered with the same name.
+func RegisterBackend(name string, factory BackendFactory) {
+	backends[name] = factory
+}
+
+// BackendNames returns the names of the registered backends, in alphabetical order.
+func BackendNames() []string {
+	var names []string
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewBackend creates the backend registered with the name.
+func NewBackend(name string, cfg BackendConfig) (Backend, error) {
+	factory, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown exporter backend: %s, available backends: %s", name, strings.Join(BackendNames(), ", "))
+	}
+
+	if cfg.Stdout == nil {
+		cfg.Stdout = os.Stdout
+	}
+	if cfg.Getenv == nil {
+		cfg.Getenv = os.Getenv
+	}
+	return factory(cfg)
+}
+
+// envmanBackend exports the outputs as env vars of the following Bitrise steps.
+type envmanBackend struct {
+	exporter export.Exporter
+}
+
+func (b envmanBackend) ExportOutput(key, value string) error {
+	return b.exporter.ExportOutputNoExpand(key, value)
+}
+
+func (b envmanBackend) MaxEnvBytes() (int, error) {
+	envmanConfigs, err := envman.GetConfigs()
+	if err != nil {
+		return 0, err
+	}
+	return envmanConfigs.EnvBytesLimitInKB * 1024, nil
+}
+
+// fileBackend only writes the changelog file, the outputs are not exported.
+type fileBackend struct{}
+
+func (fileBackend) ExportOutput(key, value string) error { return nil }
+
+func (fileBackend) MaxEnvBytes() (int, error) { return 0, nil }
+
+// stdoutBackend prints the outputs as KEY="value" lines, for local runs.
+// The values are escaped like by the dotenv backend, so every output is a single line.
+type stdoutBackend struct {
+	w io.Writer
+}
+
+func (b stdoutBackend) ExportOutput(key, value string) error {
+	_, err := fmt.Fprint(b.w, key+`="`+dotenvEscaper.Replace(value)+"\"\n")
+	return err
+}
+
+func (stdoutBackend) MaxEnvBytes() (int, error) { return 0, nil }
+
+// githubActionsBackend appends the outputs to the GITHUB_OUTPUT file of the GitHub Actions step.
+type githubActionsBackend struct {
+	pth string
+}
+
+func (b githubActionsBackend) ExportOutput(key, value string) error {
+	if !strings.Contains(value, "\n") {
+		return appendToFile(b.pth, key+"="+value+"\n")
+	}
+
+	// multiline values are enclosed by a random delimiter, which can not occur in the value
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return err
+	}
+	delimiter := "EOF_" + hex.EncodeToString(random)
+	return appendToFile(b.pth, key+"<<"+delimiter+"\n"+value+"\n"+delimiter+"\n")
+}
+
+func (githubActionsBackend) MaxEnvBytes() (int, error) { return 0, nil }
+
+// GitLabDotenvMaxBytes is the default size limit of the dotenv report artifact of a GitLab CI job.
+const GitLabDotenvMaxBytes = 5 * 1024
+
+// gitlabDotenvBackend appends the outputs to the dotenv report artifact of the GitLab CI job, which is truncated
+// when the backend is created.
+// GitLab does not support multiline values, so new lines are written as \n.
+type gitlabDotenvBackend struct {
+	pth string
+}
+
+func (b gitlabDotenvBackend) ExportOutput(key, value string) error {
+	return appendToFile(b.pth, gitlabDotenvLine(key, value))
+}
+
+func gitlabDotenvLine(key, value string) string {
+	value = strings.ReplaceAll(strings.ReplaceAll(value, "\r\n", "\n"), "\n", `\n`)
+	return key + "=" + value + "\n"
+}
+
+// EnvBytes returns the size of the line of the output in the report.
+func (gitlabDotenvBackend) EnvBytes(key, value string) int {
+	return len(gitlabDotenvLine(key, value))
+}
+
+// MaxEnvBytes returns what is left of the size limit of the report after the outputs exported so far.
+func (b gitlabDotenvBackend) MaxEnvBytes() (int, error) {
+	size := int64(0)
+	info, err := os.Stat(b.pth)
+	if err == nil {
+		size = info.Size()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return 0, err
+	}
+
+	left := GitLabDotenvMaxBytes - int(size)
+	if left <= 0 {
+		return 0, fmt.Errorf("the outputs already fill the dotenv report (%s), its size limit is %d bytes", b.pth, GitLabDotenvMaxBytes)
+	}
+	return left, nil
+}
+
+// dotenvBackend appends the outputs to a .env file as double-quoted values, the file is truncated when the backend is created.
+type dotenvBackend struct {
+	pth string
+}
+
+var dotenvEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\r", `\r`, "\n", `\n`)
+
+func (b dotenvBackend) ExportOutput(key, value string) error {
+	return appendToFile(b.pth, key+`="`+dotenvEscaper.Replace(value)+"\"\n")
+}
+
+func (dotenvBackend) MaxEnvBytes() (int, error) { return 0, nil }
+
+// truncateFile empties the file, or creates it if it does not exist.
+// The files owned by the step are truncated when the backend is created, so the outputs of a previous run are not kept.
+func truncateFile(pth string) error {
+	return os.WriteFile(pth, nil, 0644)
+}
+
+func appendToFile(pth, content string) error {
+	f, err := os.OpenFile(pth, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
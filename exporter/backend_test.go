@@ -0,0 +1,158 @@
+package exporter
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBackend(t *testing.T) {
+	_, err := NewBackend("unknown", BackendConfig{})
+	require.EqualError(t, err, "unknown exporter backend: unknown, available backends: dotenv, envman, file, github-actions, gitlab-dotenv, stdout")
+
+	_, err = NewBackend(GitHubActionsBackend, BackendConfig{Getenv: func(string) string { return "" }})
+	require.Error(t, err)
+}
+
+func TestNewBackend_truncatesFiles(t *testing.T) {
+	dir := t.TempDir()
+	cfg := BackendConfig{
+		GitLabDotenvPath: filepath.Join(dir, "changelog.env"),
+		DotenvPath:       filepath.Join(dir, ".env"),
+	}
+
+	// the outputs of the previous run
+	for _, pth := range []string{cfg.GitLabDotenvPath, cfg.DotenvPath} {
+		require.NoError(t, os.WriteFile(pth, []byte("CHANGELOG=previous run\n"), 0644))
+	}
+
+	for _, name := range []string{GitLabDotenvBackend, DotenvBackend} {
+		backend, err := NewBackend(name, cfg)
+		require.NoError(t, err)
+		require.NoError(t, backend.ExportOutput("TAG", "1.0.0"))
+	}
+
+	gitlabDotenv, err := os.ReadFile(cfg.GitLabDotenvPath)
+	require.NoError(t, err)
+	require.Equal(t, "TAG=1.0.0\n", string(gitlabDotenv))
+
+	dotenv, err := os.ReadFile(cfg.DotenvPath)
+	require.NoError(t, err)
+	require.Equal(t, "TAG=\"1.0.0\"\n", string(dotenv))
+}
+
+func TestBackends(t *testing.T) {
+	dir := t.TempDir()
+	var stdout bytes.Buffer
+	cfg := BackendConfig{
+		Stdout: &stdout,
+		Getenv: func(key string) string {
+			return map[string]string{"GITHUB_OUTPUT": filepath.Join(dir, "github_output")}[key]
+		},
+		GitLabDotenvPath: filepath.Join(dir, "changelog.env"),
+		DotenvPath:       filepath.Join(dir, ".env"),
+	}
+
+	for _, name := range []string{StdoutBackend, GitHubActionsBackend, GitLabDotenvBackend, DotenvBackend, FileBackend} {
+		backend, err := NewBackend(name, cfg)
+		require.NoError(t, err)
+		require.NoError(t, backend.ExportOutput("TAG", "1.0.0"))
+		require.NoError(t, backend.ExportOutput("CHANGELOG", "* \"feat\": $HOME\n* fix"))
+
+		maxEnvBytes, err := backend.MaxEnvBytes()
+		require.NoError(t, err)
+		if name == GitLabDotenvBackend {
+			require.Equal(t, GitLabDotenvMaxBytes-len("TAG=1.0.0\nCHANGELOG=* \"feat\": $HOME\\n* fix\n"), maxEnvBytes)
+		} else {
+			require.Zero(t, maxEnvBytes)
+		}
+	}
+
+	require.Equal(t, "TAG=\"1.0.0\"\nCHANGELOG=\"* \\\"feat\\\": \\$HOME\\n* fix\"\n", stdout.String())
+
+	githubOutput, err := os.ReadFile(filepath.Join(dir, "github_output"))
+	require.NoError(t, err)
+	require.Regexp(t, regexp.MustCompile("^TAG=1.0.0\nCHANGELOG<<(EOF_[0-9a-f]{32})\n\\* \"feat\": \\$HOME\n\\* fix\n(EOF_[0-9a-f]{32})\n$"), string(githubOutput))
+
+	gitlabDotenv, err := os.ReadFile(filepath.Join(dir, "changelog.env"))
+	require.NoError(t, err)
+	require.Equal(t, "TAG=1.0.0\nCHANGELOG=* \"feat\": $HOME\\n* fix\n", string(gitlabDotenv))
+
+	dotenv, err := os.ReadFile(filepath.Join(dir, ".env"))
+	require.NoError(t, err)
+	require.Equal(t, "TAG=\"1.0.0\"\nCHANGELOG=\"* \\\"feat\\\": \\$HOME\\n* fix\"\n", string(dotenv))
+}
+
+func TestMulti(t *testing.T) {
+	dir := t.TempDir()
+	var stdout bytes.Buffer
+	cfg := BackendConfig{Stdout: &stdout, DotenvPath: filepath.Join(dir, ".env")}
+
+	m, err := NewMulti("CHANGELOG", filepath.Join(dir, "CHANGELOG.md"), []string{StdoutBackend, DotenvBackend, StdoutBackend}, cfg)
+	require.NoError(t, err)
+	require.NoError(t, m.WriteFile("content"))
+	require.NoFileExists(t, filepath.Join(dir, "CHANGELOG.md"))
+	require.Empty(t, m.Filepath())
+	require.NoError(t, m.ExportEnv("content", nil))
+	require.NoError(t, m.ExportOutputs(Output{Key: "TAG", Value: "1.0.0"}))
+	require.Equal(t, "CHANGELOG=\"content\"\nTAG=\"1.0.0\"\n", stdout.String())
+
+	m, err = NewMulti("CHANGELOG", filepath.Join(dir, "CHANGELOG.md"), []string{FileBackend}, cfg)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "CHANGELOG.md"), m.Filepath())
+	m = m.WithMerge(func(existing, content string) (string, error) { return content + existing, nil })
+	require.NoError(t, m.WriteFile("old"))
+	require.NoError(t, m.WriteFile("new "))
+	b, err := os.ReadFile(filepath.Join(dir, "CHANGELOG.md"))
+	require.NoError(t, err)
+	require.Equal(t, "new old", string(b))
+
+	_, err = NewMulti("CHANGELOG", "", []string{"unknown"}, cfg)
+	require.Error(t, err)
+}
+
+func TestMulti_ExportEnv(t *testing.T) {
+	dir := t.TempDir()
+	var stdout bytes.Buffer
+	gitlabDotenvPath := filepath.Join(dir, "changelog.env")
+	cfg := BackendConfig{Stdout: &stdout, GitLabDotenvPath: gitlabDotenvPath}
+
+	m, err := NewMulti("CHANGELOG", "", []string{StdoutBackend, GitLabDotenvBackend}, cfg)
+	require.NoError(t, err)
+	require.NoError(t, m.ExportOutput("TAG", "1.0.0"))
+
+	changelog := strings.Repeat("* fix\n", GitLabDotenvMaxBytes/6+1)
+	var calls int
+	truncate := func(value string, maxBytes int, size func(string) int) (string, error) {
+		calls++
+		// what is left of the report after TAG=1.0.0
+		require.Equal(t, GitLabDotenvMaxBytes-len("TAG=1.0.0\n"), maxBytes)
+		// the escaped line with the key
+		require.Equal(t, len("CHANGELOG=* fix\\n\n"), size("* fix\n"))
+		return "* fix\n", nil
+	}
+	require.NoError(t, m.ExportEnv(changelog, truncate))
+	require.Equal(t, 1, calls)
+
+	// the stdout backend has no limit, the changelog is not truncated
+	require.Equal(t, "TAG=\"1.0.0\"\nCHANGELOG=\""+strings.ReplaceAll(changelog, "\n", `\n`)+"\"\n", stdout.String())
+	gitlabDotenv, err := os.ReadFile(gitlabDotenvPath)
+	require.NoError(t, err)
+	require.Equal(t, "TAG=1.0.0\nCHANGELOG=* fix\\n\n", string(gitlabDotenv))
+
+	// the report is full
+	require.NoError(t, os.WriteFile(gitlabDotenvPath, bytes.Repeat([]byte("x"), GitLabDotenvMaxBytes), 0644))
+	err = m.ExportEnv("* fix\n", truncate)
+	require.EqualError(t, err, "gitlab-dotenv: unable to get the size limit: the outputs already fill the dotenv report ("+gitlabDotenvPath+"), its size limit is 5120 bytes")
+
+	truncateErr := func(string, int, func(string) int) (string, error) { return "", errors.New("too long") }
+	m, err = NewMulti("CHANGELOG", "", []string{GitLabDotenvBackend}, BackendConfig{GitLabDotenvPath: filepath.Join(dir, "other.env")})
+	require.NoError(t, err)
+	require.EqualError(t, m.ExportEnv(changelog, truncateErr), "gitlab-dotenv: too long")
+}
@@ -1,41 +1,32 @@
 package exporter
 
 import (
-	"github.com/bitrise-io/envman/envman"
-	"github.com/bitrise-io/go-steputils/v2/export"
+	"errors"
+	"os"
+
 	"github.com/bitrise-io/go-utils/fileutil"
-	"github.com/bitrise-io/go-utils/v2/command"
-	"github.com/bitrise-io/go-utils/v2/env"
 )
 
-type EnvAndFile struct {
-	envKey, filepath string
-	exporter export.Exporter
-}
-
-func New(envKey, filepath string) EnvAndFile {
-	exporter := export.NewExporter(command.NewFactory(env.NewRepository()))
-	return EnvAndFile{envKey: envKey, filepath: filepath, exporter: exporter}
-}
-
-func (e EnvAndFile) EnvKey() string { return e.envKey }
+// MergeFunc merges the new content into the existing content of the file.
+type MergeFunc func(existing, content string) (string, error)
 
-func (e EnvAndFile) Filepath() string { return e.filepath }
+func writeFile(pth, content string, merge MergeFunc) error {
+	if merge != nil {
+		existing, err := os.ReadFile(pth)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
 
-func (e EnvAndFile) WriteFile(content string) error {
-	return fileutil.WriteStringToFile(e.Filepath(), content)
-}
-
-func (e EnvAndFile) ExportEnv(value string) error {
-	// Do not expand env vars in the generated changelog because the input is beyond the control of the step,
-	// and it could lead to surprising behavior.
-	return e.exporter.ExportOutputNoExpand(e.EnvKey(), value)
+		content, err = merge(string(existing), content)
+		if err != nil {
+			return err
+		}
+	}
+	return fileutil.WriteStringToFile(pth, content)
 }
 
-func (e EnvAndFile) MaxEnvBytes() (int, error) {
-	envmanConfigs, err := envman.GetConfigs()
-	if err != nil {
-		return 0, err
-	}
-	return envmanConfigs.EnvBytesLimitInKB * 1024, nil
+// Output is an additional output of the step.
+type Output struct {
+	Key   string
+	Value string
 }
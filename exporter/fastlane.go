@@ -0,0 +1,49 @@
+package exporter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+// FastlaneMaxChars is the limit of the App Store release notes of a locale.
+const FastlaneMaxChars = 4000
+
+// FastlaneMetadata writes release notes into the metadata directory of fastlane deliver,
+// a <locale>/release_notes.txt file per locale.
+type FastlaneMetadata struct {
+	dir     string
+	locales []string
+}
+
+func NewFastlaneMetadata(dir string, locales []string) FastlaneMetadata {
+	return FastlaneMetadata{dir: dir, locales: locales}
+}
+
+func (f FastlaneMetadata) Dir() string { return f.dir }
+
+// Filepath returns the path of the release notes of the locale (fastlane/metadata/en-US/release_notes.txt).
+func (f FastlaneMetadata) Filepath(locale string) string {
+	return filepath.Join(f.dir, locale, "release_notes.txt")
+}
+
+// WriteFiles writes the release notes for every locale, keeping the other metadata files of the locales.
+// The release notes are written as they are, without a trailing new line, so the file is at most FastlaneMaxChars characters.
+// It fails if the release notes are longer than FastlaneMaxChars characters.
+func (f FastlaneMetadata) WriteFiles(content string) error {
+	if length := utf8.RuneCountInString(content); length > FastlaneMaxChars {
+		return fmt.Errorf("release notes are %d characters long, the App Store allows at most %d", length, FastlaneMaxChars)
+	}
+
+	for _, locale := range f.locales {
+		pth := f.Filepath(locale)
+		if err := os.MkdirAll(filepath.Dir(pth), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(pth, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
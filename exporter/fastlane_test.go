@@ -0,0 +1,36 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFastlaneMetadata_WriteFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "fastlane", "metadata")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "en-US"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "en-US", "description.txt"), []byte("My app"), 0644))
+
+	f := NewFastlaneMetadata(dir, []string{"en-US", "de-DE"})
+	require.NoError(t, f.WriteFiles("• Dark mode"))
+	for _, locale := range []string{"en-US", "de-DE"} {
+		b, err := os.ReadFile(filepath.Join(dir, locale, "release_notes.txt"))
+		require.NoError(t, err)
+		require.Equal(t, "• Dark mode", string(b))
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "en-US", "description.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "My app", string(b))
+
+	require.Error(t, f.WriteFiles(strings.Repeat("ü", FastlaneMaxChars+1)))
+
+	require.NoError(t, f.WriteFiles(strings.Repeat("ü", FastlaneMaxChars)))
+	b, err = os.ReadFile(filepath.Join(dir, "en-US", "release_notes.txt"))
+	require.NoError(t, err)
+	require.Equal(t, FastlaneMaxChars, utf8.RuneCount(b))
+}
@@ -0,0 +1,113 @@
+package exporter
+
+import (
+	"fmt"
+)
+
+// Multi writes the changelog file if the file backend is selected,
+// and exports the changelog and the outputs with every selected backend.
+type Multi struct {
+	envKey, filepath string
+	writeFile        bool
+	names            []string
+	backends         []Backend
+	merge            MergeFunc
+}
+
+// TruncateFunc shortens the value to at most maxBytes, measured by size.
+type TruncateFunc func(value string, maxBytes int, size func(value string) int) (string, error)
+
+// NewMulti returns an exporter with the backends registered with the given names (envman, file, stdout, ...).
+func NewMulti(envKey, filepath string, names []string, cfg BackendConfig) (Multi, error) {
+	m := Multi{envKey: envKey, filepath: filepath}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		backend, err := NewBackend(name, cfg)
+		if err != nil {
+			return Multi{}, err
+		}
+		m.names = append(m.names, name)
+		m.backends = append(m.backends, backend)
+		m.writeFile = m.writeFile || name == FileBackend
+	}
+	return m, nil
+}
+
+// WithMerge returns the exporter which merges the content into the existing file instead of overwriting it.
+func (m Multi) WithMerge(merge MergeFunc) Multi {
+	m.merge = merge
+	return m
+}
+
+func (m Multi) EnvKey() string { return m.envKey }
+
+// Filepath returns the path of the changelog file, or an empty string if the file backend is not selected.
+func (m Multi) Filepath() string {
+	if !m.writeFile {
+		return ""
+	}
+	return m.filepath
+}
+
+// WriteFile writes the changelog file, if the file backend is selected.
+func (m Multi) WriteFile(content string) error {
+	if !m.writeFile {
+		return nil
+	}
+	return writeFile(m.filepath, content, m.merge)
+}
+
+// ExportEnv exports the changelog with every backend. If the changelog exceeds the size limit of a backend,
+// the changelog shortened by truncate is exported with that backend.
+func (m Multi) ExportEnv(value string, truncate TruncateFunc) error {
+	for i, backend := range m.backends {
+		size := func(value string) int { return len(value) }
+		if sized, ok := backend.(sizedBackend); ok {
+			size = func(value string) int { return sized.EnvBytes(m.envKey, value) }
+		}
+
+		maxEnvBytes, err := backend.MaxEnvBytes()
+		if err != nil {
+			return fmt.Errorf("%s: unable to get the size limit: %s", m.names[i], err)
+		}
+
+		exported := value
+		if maxEnvBytes > 0 && size(value) > maxEnvBytes {
+			if exported, err = truncate(value, maxEnvBytes, size); err != nil {
+				return fmt.Errorf("%s: %s", m.names[i], err)
+			}
+		}
+
+		// Do not expand env vars in the generated changelog because the input is beyond the control of the step,
+		// and it could lead to surprising behavior.
+		if err := backend.ExportOutput(m.envKey, exported); err != nil {
+			return fmt.Errorf("%s: %s", m.names[i], err)
+		}
+	}
+	return nil
+}
+
+// ExportOutput exports an additional output of the step with every backend, without expanding env vars in the value.
+func (m Multi) ExportOutput(key, value string) error {
+	for i, backend := range m.backends {
+		if err := backend.ExportOutput(key, value); err != nil {
+			return fmt.Errorf("%s: %s", m.names[i], err)
+		}
+	}
+	return nil
+}
+
+// ExportOutputs exports the additional outputs of the step, without expanding env vars in the values.
+func (m Multi) ExportOutputs(outputs ...Output) error {
+	for _, output := range outputs {
+		if err := m.ExportOutput(output.Key, output.Value); err != nil {
+			return fmt.Errorf("unable to export %s: %s", output.Key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package exporter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+// WhatsNewMaxChars is the limit of the Google Play release notes of a locale.
+const WhatsNewMaxChars = 500
+
+// WhatsNew writes release notes into the whatsnew directory of the Google Play deploy step,
+// a whatsnew-<locale> file per locale.
+type WhatsNew struct {
+	dir     string
+	locales []string
+}
+
+func NewWhatsNew(dir string, locales []string) WhatsNew {
+	return WhatsNew{dir: dir, locales: locales}
+}
+
+func (w WhatsNew) Dir() string { return w.dir }
+
+// Filepath returns the path of the release notes of the locale (whatsnew/whatsnew-en-US).
+func (w WhatsNew) Filepath(locale string) string {
+	return filepath.Join(w.dir, "whatsnew-"+locale)
+}
+
+// WriteFiles writes the release notes for every locale.
+// It fails if the release notes are longer than WhatsNewMaxChars characters.
+func (w WhatsNew) WriteFiles(content string) error {
+	if length := utf8.RuneCountInString(content); length > WhatsNewMaxChars {
+		return fmt.Errorf("release notes are %d characters long, Google Play allows at most %d", length, WhatsNewMaxChars)
+	}
+
+	if err := os.MkdirAll(w.dir, 0755); err != nil {
+		return err
+	}
+	for _, locale := range w.locales {
+		if err := os.WriteFile(w.Filepath(locale), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
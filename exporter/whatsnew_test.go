@@ -0,0 +1,25 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhatsNew_WriteFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "whatsnew")
+	w := NewWhatsNew(dir, []string{"en-US", "de-DE"})
+
+	require.NoError(t, w.WriteFiles("• Dark mode"))
+	for _, name := range []string{"whatsnew-en-US", "whatsnew-de-DE"} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		require.Equal(t, "• Dark mode", string(b))
+	}
+
+	require.NoError(t, w.WriteFiles(strings.Repeat("ü", WhatsNewMaxChars)))
+	require.Error(t, w.WriteFiles(strings.Repeat("ü", WhatsNewMaxChars+1)))
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/git"
+)
+
+// commitFilter selects the commits listed in the changelog.
+// Every include list which is set has to match the commit, and none of the exclude lists can match it.
+type commitFilter struct {
+	IncludeMessages []*regexp.Regexp
+	ExcludeMessages []*regexp.Regexp
+	// IncludeAuthors and ExcludeAuthors are author names or emails (case-insensitive).
+	IncludeAuthors []string
+	ExcludeAuthors []string
+	// IncludePaths and ExcludePaths are git pathspecs (app/, docs/, *.md).
+	IncludePaths []string
+	ExcludePaths []string
+}
+
+func newCommitFilter(includeMessages, excludeMessages, includeAuthors, excludeAuthors, includePaths, excludePaths []string) (commitFilter, error) {
+	f := commitFilter{
+		IncludeAuthors: nonEmptyLines(includeAuthors),
+		ExcludeAuthors: nonEmptyLines(excludeAuthors),
+		IncludePaths:   nonEmptyLines(includePaths),
+		ExcludePaths:   nonEmptyLines(excludePaths),
+	}
+
+	var err error
+	if f.IncludeMessages, err = compileRegexps(includeMessages); err != nil {
+		return commitFilter{}, fmt.Errorf("invalid include message pattern: %s", err)
+	}
+	if f.ExcludeMessages, err = compileRegexps(excludeMessages); err != nil {
+		return commitFilter{}, fmt.Errorf("invalid exclude message pattern: %s", err)
+	}
+
+	return f, nil
+}
+
+func nonEmptyLines(lines []string) []string {
+	var nonEmpty []string
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			nonEmpty = append(nonEmpty, line)
+		}
+	}
+	return nonEmpty
+}
+
+func compileRegexps(patterns []string) ([]*regexp.Regexp, error) {
+	var regexps []*regexp.Regexp
+	for _, pattern := range nonEmptyLines(patterns) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		regexps = append(regexps, re)
+	}
+	return regexps, nil
+}
+
+// filterReleases removes the commits not matching the filter from the releases,
+// and returns the number of removed commits.
+func filterReleases(dir string, releases []release, f commitFilter) ([]release, int, error) {
+	filtered := 0
+	for i, r := range releases {
+		commits, err := f.filter(dir, r.Commits)
+		if err != nil {
+			return nil, 0, err
+		}
+		filtered += len(r.Commits) - len(commits)
+		releases[i].Commits = commits
+	}
+	return releases, filtered, nil
+}
+
+func (f commitFilter) filter(dir string, commits []git.Commit) ([]git.Commit, error) {
+	var kept []git.Commit
+	for _, commit := range commits {
+		if f.matchMessage(commit) && f.matchAuthor(commit) {
+			kept = append(kept, commit)
+		}
+	}
+
+	if len(kept) == 0 || (len(f.IncludePaths) == 0 && len(f.ExcludePaths) == 0) {
+		return kept, nil
+	}
+
+	var hashes []string
+	for _, commit := range kept {
+		hashes = append(hashes, commit.Hash)
+	}
+	touching, err := git.CommitsTouchingPaths(dir, hashes, f.IncludePaths, f.ExcludePaths)
+	if err != nil {
+		return nil, err
+	}
+
+	var touchingCommits []git.Commit
+	for _, commit := range kept {
+		if touching[commit.Hash] {
+			touchingCommits = append(touchingCommits, commit)
+		}
+	}
+	return touchingCommits, nil
+}
+
+func (f commitFilter) matchMessage(commit git.Commit) bool {
+	message := commit.Message
+	if commit.Body != "" {
+		message += "\n\n" + commit.Body
+	}
+
+	if len(f.IncludeMessages) > 0 && !matchAnyRegexp(f.IncludeMessages, message) {
+		return false
+	}
+	return !matchAnyRegexp(f.ExcludeMessages, message)
+}
+
+func (f commitFilter) matchAuthor(commit git.Commit) bool {
+	if len(f.IncludeAuthors) > 0 && !isAuthor(commit, f.IncludeAuthors) {
+		return false
+	}
+	return !isAuthor(commit, f.ExcludeAuthors)
+}
+
+func matchAnyRegexp(regexps []*regexp.Regexp, s string) bool {
+	for _, re := range regexps {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAuthor reports whether the author name or email of the commit is in the list.
+func isAuthor(commit git.Commit, authors []string) bool {
+	for _, author := range authors {
+		if strings.EqualFold(author, commit.Author) || strings.EqualFold(author, commit.AuthorEmail) {
+			return true
+		}
+	}
+	return false
+}
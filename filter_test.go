@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/git"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_filterReleases(t *testing.T) {
+	commits := []git.Commit{
+		{Hash: "1", Message: "Add login screen", Author: "Alice", AuthorEmail: "alice@example.com"},
+		{Hash: "2", Message: "Bump version", Author: "Alice", AuthorEmail: "alice@example.com"},
+		{Hash: "3", Message: "Update docs", Body: "[skip ci]", Author: "Bob", AuthorEmail: "bob@example.com"},
+		{Hash: "4", Message: "Bump git from 1.5.0 to 1.11.0", Author: "dependabot[bot]", AuthorEmail: "bot@example.com"},
+	}
+
+	tests := []struct {
+		name            string
+		includeMessages []string
+		excludeMessages []string
+		includeAuthors  []string
+		excludeAuthors  []string
+		want            []string
+	}{
+		{name: "no filters", want: []string{"1", "2", "3", "4"}},
+		{name: "exclude messages", excludeMessages: []string{"^Bump version", `\[skip ci\]`}, want: []string{"1", "4"}},
+		{name: "include messages", includeMessages: []string{"^Bump", ""}, want: []string{"2", "4"}},
+		{name: "exclude authors", excludeAuthors: []string{"DEPENDABOT[BOT]", "bob@example.com"}, want: []string{"1", "2"}},
+		{name: "include authors", includeAuthors: []string{"alice@example.com"}, want: []string{"1", "2"}},
+		{name: "combined", includeAuthors: []string{"Alice"}, excludeMessages: []string{"^Bump"}, want: []string{"1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newCommitFilter(tt.includeMessages, tt.excludeMessages, tt.includeAuthors, tt.excludeAuthors, nil, nil)
+			require.NoError(t, err)
+
+			releases, filtered, err := filterReleases("", []release{{Commits: append([]git.Commit{}, commits...)}}, f)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, commitHashes(releases[0].Commits))
+			require.Equal(t, len(commits)-len(tt.want), filtered)
+		})
+	}
+}
+
+func Test_newCommitFilter(t *testing.T) {
+	_, err := newCommitFilter([]string{"("}, nil, nil, nil, nil, nil)
+	require.EqualError(t, err, "invalid include message pattern: error parsing regexp: missing closing ): `(`")
+
+	_, err = newCommitFilter(nil, []string{"["}, nil, nil, nil, nil)
+	require.Error(t, err)
+
+	f, err := newCommitFilter(nil, nil, nil, nil, []string{" app/ ", ""}, []string{"docs/"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"app/"}, f.IncludePaths)
+	require.Equal(t, []string{"docs/"}, f.ExcludePaths)
+}
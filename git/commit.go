@@ -4,11 +4,68 @@ import (
 	"time"
 )
 
+// CoAuthorTrailer is the trailer key crediting the co-authors of a commit.
+const CoAuthorTrailer = "Co-authored-by"
+
 // Commit ...
 type Commit struct {
-	Hash    string
-	Message string
-	Date    time.Time
-	Author  string
-	Tag     string
+	Hash    string `json:"hash"`
+	Message string `json:"message"`
+	Body    string `json:"body"`
+	// Date is the commit date, AuthorDate is the date the change was originally made.
+	Date           time.Time `json:"date"`
+	AuthorDate     time.Time `json:"author_date"`
+	Author         string    `json:"author"`
+	AuthorEmail    string    `json:"author_email"`
+	Committer      string    `json:"committer"`
+	CommitterEmail string    `json:"committer_email"`
+	CoAuthors      []Person  `json:"co_authors,omitempty"`
+	Tag            string    `json:"tag,omitempty"`
+	URL            string    `json:"url,omitempty"`
+	Parents        []string  `json:"parents,omitempty"`
+
+	Trailers    []Trailer    `json:"trailers,omitempty"`
+	PullRequest *PullRequest `json:"pull_request,omitempty"`
+	// Fixups are the follow-up commits of the commit (fixup! commits, further commits of the same pull request).
+	Fixups []Commit `json:"fixups,omitempty"`
+
+	ConventionalCommit
+}
+
+func newCommit(hash, message, body string, date time.Time, author string) Commit {
+	commit := Commit{
+		Hash:    hash,
+		Message: message,
+		Body:    body,
+		Date:    date,
+		Author:  author,
+
+		Trailers: parseTrailers(body),
+	}
+	for _, coAuthor := range commit.TrailerValues(CoAuthorTrailer) {
+		commit.CoAuthors = append(commit.CoAuthors, ParsePerson(coAuthor))
+	}
+	if pr, ok := parsePullRequest(message, body); ok {
+		commit.PullRequest = &pr
+	}
+
+	// the Conventional Commits header of pull requests is their title
+	header := message
+	if commit.PullRequest != nil {
+		header = commit.PullRequest.Title
+	}
+	if cc, ok := ParseConventionalCommit(header, body); ok {
+		commit.ConventionalCommit = cc
+	}
+	return commit
+}
+
+// Contributors returns the author and the co-authors of the commit.
+func (c Commit) Contributors() []Person {
+	return append([]Person{{Name: c.Author, Email: c.AuthorEmail}}, c.CoAuthors...)
+}
+
+// IsMerge reports whether the commit has more than one parent.
+func (c Commit) IsMerge() bool {
+	return len(c.Parents) > 1
 }
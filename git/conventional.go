@@ -0,0 +1,93 @@
+package git
+
+import (
+	"regexp"
+	"strings"
+)
+
+// BreakingChangeToken is the footer token marking a breaking change in Conventional Commits.
+const BreakingChangeToken = "BREAKING CHANGE"
+
+// header: <type>[(<scope>)][!]: <description>
+var conventionalHeaderRegexp = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9-]*)(?:\(([^()\r\n]*)\))?(!)?: (.+)$`)
+
+// footer: <token>: <value> or <token> #<value>
+var conventionalFooterRegexp = regexp.MustCompile(`^(BREAKING CHANGE|BREAKING-CHANGE|[a-zA-Z][a-zA-Z0-9-]*)(?:: | #)(.*)$`)
+
+// Footer is a Conventional Commits footer (git trailer) of a commit message.
+type Footer struct {
+	Token string `json:"token"`
+	Value string `json:"value"`
+}
+
+// ConventionalCommit holds the parts of a commit message following the Conventional Commits specification.
+type ConventionalCommit struct {
+	Type        string   `json:"type,omitempty"`
+	Scope       string   `json:"scope,omitempty"`
+	Breaking    bool     `json:"breaking"`
+	Description string   `json:"description,omitempty"`
+	Footers     []Footer `json:"footers,omitempty"`
+}
+
+// ParseConventionalCommit parses the given commit subject and body according to the Conventional Commits
+// specification (https://www.conventionalcommits.org/en/v1.0.0/).
+// The returned bool is false if the subject is not a conventional commit header.
+func ParseConventionalCommit(subject, body string) (ConventionalCommit, bool) {
+	match := conventionalHeaderRegexp.FindStringSubmatch(strings.TrimSpace(subject))
+	if match == nil {
+		return ConventionalCommit{}, false
+	}
+
+	cc := ConventionalCommit{
+		Type:        strings.ToLower(match[1]),
+		Scope:       strings.TrimSpace(match[2]),
+		Breaking:    match[3] == "!",
+		Description: strings.TrimSpace(match[4]),
+		Footers:     parseFooters(body),
+	}
+	for _, footer := range cc.Footers {
+		if footer.Token == BreakingChangeToken {
+			cc.Breaking = true
+		}
+	}
+
+	return cc, true
+}
+
+// parseFooters parses the footers from the last paragraph of the commit body.
+func parseFooters(body string) []Footer {
+	_, footers := splitFooters(body)
+	return footers
+}
+
+// splitFooters splits the commit body into the text and the footers of its last paragraph.
+func splitFooters(body string) (string, []Footer) {
+	body = strings.TrimSpace(strings.ReplaceAll(body, "\r\n", "\n"))
+	paragraphs := strings.Split(body, "\n\n")
+	lastParagraph := paragraphs[len(paragraphs)-1]
+
+	var footers []Footer
+	for _, line := range strings.Split(lastParagraph, "\n") {
+		if match := conventionalFooterRegexp.FindStringSubmatch(line); match != nil {
+			token := match[1]
+			if token == "BREAKING-CHANGE" {
+				token = BreakingChangeToken
+			}
+			footers = append(footers, Footer{Token: token, Value: strings.TrimSpace(match[2])})
+			continue
+		}
+
+		if len(footers) == 0 {
+			// the paragraph does not start with a footer, so it is part of the body
+			return body, nil
+		}
+		// continuation of a multi-line footer value
+		last := &footers[len(footers)-1]
+		last.Value = strings.TrimSpace(last.Value + "\n" + line)
+	}
+
+	if len(footers) == 0 {
+		return body, nil
+	}
+	return strings.TrimSpace(strings.Join(paragraphs[:len(paragraphs)-1], "\n\n")), footers
+}
@@ -0,0 +1,70 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConventionalCommit(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		body    string
+		want    ConventionalCommit
+		wantOk  bool
+	}{
+		{
+			name:    "not conventional",
+			subject: "Tool-248 firebase (#7)",
+			wantOk:  false,
+		},
+		{
+			name:    "type only",
+			subject: "fix: parsing git commits",
+			want:    ConventionalCommit{Type: "fix", Description: "parsing git commits"},
+			wantOk:  true,
+		},
+		{
+			name:    "type, scope and breaking flag",
+			subject: "Feat(api)!: drop v1 endpoints",
+			want:    ConventionalCommit{Type: "feat", Scope: "api", Breaking: true, Description: "drop v1 endpoints"},
+			wantOk:  true,
+		},
+		{
+			name:    "breaking change footer",
+			subject: "feat: new config format",
+			body: `The config is now YAML.
+
+BREAKING CHANGE: the JSON config is not
+supported anymore
+Refs #123
+Reviewed-by: Bob`,
+			want: ConventionalCommit{
+				Type:        "feat",
+				Breaking:    true,
+				Description: "new config format",
+				Footers: []Footer{
+					{Token: BreakingChangeToken, Value: "the JSON config is not\nsupported anymore"},
+					{Token: "Refs", Value: "123"},
+					{Token: "Reviewed-by", Value: "Bob"},
+				},
+			},
+			wantOk: true,
+		},
+		{
+			name:    "body without footers",
+			subject: "docs: readme",
+			body:    "Some text\nNote: this is not a footer paragraph",
+			want:    ConventionalCommit{Type: "docs", Description: "readme"},
+			wantOk:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseConventionalCommit(tt.subject, tt.body)
+			require.Equal(t, tt.wantOk, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -2,230 +2,160 @@ package git
 
 import (
 	"fmt"
-	"sort"
-	"strconv"
+	"io"
 	"strings"
-	"time"
 
 	"github.com/bitrise-io/go-utils/command"
 	"github.com/pkg/errors"
 )
 
-const (
-	hashPrefix    = "commit: "
-	datePrefix    = "date: "
-	authorPrefix  = "author: "
-	messagePrefix = "message: "
-)
+// peeledAtom returns the for-each-ref field of the object a tag points to,
+// the tagged object for annotated tags and the referenced object for lightweight tags.
+func peeledAtom(atom string) string {
+	return "%(if)%(*objectname)%(then)%(*" + atom + ")%(else)%(" + atom + ")%(end)"
+}
 
-func parseDate(unixTimeStampStr string) (time.Time, error) {
-	i, err := strconv.ParseInt(unixTimeStampStr, 10, 64)
+// tagRefFormat lists the fields of a tag: name, type of the tagged object and the logFormat fields of the tagged commit.
+var tagRefFormat = "--format=%1e%(refname:strip=2)%00" +
+	peeledAtom("objecttype") + "%00" +
+	peeledAtom("objectname") + "%00" +
+	peeledAtom("parent") + "%00" +
+	peeledAtom("committerdate:unix") + "%00" +
+	peeledAtom("authordate:unix") + "%00" +
+	peeledAtom("authorname") + "%00" +
+	peeledAtom("authoremail") + "%00" +
+	peeledAtom("committername") + "%00" +
+	peeledAtom("committeremail") + "%00" +
+	peeledAtom("subject") + "%00" +
+	peeledAtom("body") + "%00"
+
+// TaggedCommits returns the commits of the tags matching the pattern, ordered by the tag's semantic version
+// (or by the commit date if the tags are not semantic versions).
+// Every tag is resolved by a single git for-each-ref call.
+func TaggedCommits(repoDir string, pattern TagPattern) ([]Commit, error) {
+	output, err := startGit(repoDir, "for-each-ref", tagRefFormat, "refs/tags")
 	if err != nil {
-		return time.Time{}, err
-	}
-	if i < 0 {
-		return time.Time{}, errors.WithStack(fmt.Errorf("invalid time stamp (%s)", unixTimeStampStr))
+		return nil, err
 	}
-	return time.Unix(i, 0), nil
-}
 
-func parseCommit(out string) (Commit, error) {
-	// commit b738generate-release19a4d5
-	// commit: b738generate-release19a4d5
-	// date: 1455631980
-	// author: Bitrise Bot
-	// message: Merge branch 'master' of github.com:bitrise-bot/generate-changelog
-
-	/*
-		commit: 7d32generate-release43a6e9
-		date: 1455788198
-		author: Bitrise Developer
-		message: FIX: parsing git commits
-	*/
-	hash := ""
-	dateStr := ""
-	author := ""
-	message := ""
-	messageStart := false
-	for _, line := range strings.Split(out, "\n") {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, hashPrefix) {
-			hash = strings.TrimPrefix(line, hashPrefix)
-		} else if strings.HasPrefix(line, datePrefix) {
-			dateStr = strings.TrimPrefix(line, datePrefix)
-		} else if strings.HasPrefix(line, authorPrefix) {
-			author = strings.TrimPrefix(line, authorPrefix)
-		} else if strings.HasPrefix(line, messagePrefix) {
-			messageStart = true
+	records := newRecordReader(output.stdout)
+	var taggedCommits []Commit
+	for {
+		fields, err := records.next(2 + logFormatFields)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			output.kill()
+			return nil, errors.WithStack(fmt.Errorf("Failed to parse tags: %s", err))
 		}
 
-		if messageStart {
-			if strings.HasPrefix(line, messagePrefix) {
-				message += strings.TrimPrefix(line, messagePrefix)
-			} else {
-				message += fmt.Sprintf("\n%s", line)
-			}
+		tag, objectType := fields[0], fields[1]
+		if objectType != "commit" || !pattern.Match(tag) {
+			continue
 		}
-	}
 
-	if hash == "" || dateStr == "" || author == "" {
-		return Commit{}, errors.WithStack(fmt.Errorf("missing 'date: ' / 'author: ' / 'commit: ' fields in: %s", out))
-	}
+		commit, err := commitFromFields(fields[2:])
+		if err != nil {
+			output.kill()
+			return nil, errors.WithStack(fmt.Errorf("Failed to parse the commit of tag (%s): %s", tag, err))
+		}
+		commit.Tag = tag
 
-	date, err := parseDate(dateStr)
-	if err != nil {
-		return Commit{}, err
+		taggedCommits = append(taggedCommits, commit)
+	}
+	if err := output.wait(); err != nil {
+		return nil, err
 	}
 
-	return Commit{
-		Hash:    hash,
-		Message: message,
-		Date:    date,
-		Author:  author,
-	}, nil
+	return sortTaggedCommits(taggedCommits, pattern), nil
 }
 
-// TaggedCommits ...
-func TaggedCommits(repoDir string) ([]Commit, error) {
-	cmd := command.New("git", "tag", "--list").SetDir(repoDir)
-	out, err := cmd.RunAndReturnTrimmedCombinedOutput()
+// RevisionCommit returns the commit the given revision (tag, branch, SHA, HEAD~20, ...) points to.
+func RevisionCommit(repoDir, revision string) (Commit, error) {
+	commits, err := logCommits(repoDir, "-1", revision, "--")
 	if err != nil {
-		return nil, errors.WithStack(fmt.Errorf("%s failed: %s", cmd.PrintableCommandArgs(), out))
+		return Commit{}, err
 	}
-
-	if out == "" {
-		return nil, nil
+	if len(commits) == 0 {
+		return Commit{}, errors.WithStack(fmt.Errorf("no commit found for revision: %s", revision))
 	}
+	return commits[0], nil
+}
 
-	var taggedCommits []Commit
-	for _, tag := range strings.Split(out, "\n") {
-		tag = strings.TrimSpace(tag)
-
-		cmd := command.New("git", "rev-list", "-n", "1", `--pretty=format:commit: %H%ndate: %ct%nauthor: %an%nmessage: %s`, tag).SetDir(repoDir)
-		out, err := cmd.RunAndReturnTrimmedCombinedOutput()
-		if err != nil {
-			return nil, errors.WithStack(fmt.Errorf("%s failed: %s", cmd.PrintableCommandArgs(), out))
-		}
-
-		commit, err := parseCommit(out)
-		if err != nil {
-			return nil, errors.WithStack(fmt.Errorf("Failed to parse commit: %#v", err))
+// IsAncestor reports whether the ancestor revision is reachable from the descendant revision.
+func IsAncestor(repoDir, ancestor, descendant string) (bool, error) {
+	cmd := command.New("git", "merge-base", "--is-ancestor", ancestor, descendant).SetDir(repoDir)
+	out, err := cmd.RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		if cmd.GetCmd().ProcessState != nil && cmd.GetCmd().ProcessState.ExitCode() == 1 {
+			return false, nil
 		}
-		commit.Tag = tag
-
-		taggedCommits = append(taggedCommits, commit)
+		return false, errors.WithStack(fmt.Errorf("%s failed: %s", cmd.PrintableCommandArgs(), out))
 	}
-
-	sort.Slice(taggedCommits, func(i, j int) bool {
-		return taggedCommits[i].Date.Before(taggedCommits[j].Date)
-	})
-	return taggedCommits, nil
+	return true, nil
 }
 
 // FirstCommit ...
 func FirstCommit(repoDir string) (Commit, error) {
-	cmd := command.New("git", "rev-list", "--max-parents=0", `--pretty=format:commit: %H%ndate: %ct%nauthor: %an%nmessage: %s`, "HEAD").SetDir(repoDir)
-	out, err := cmd.RunAndReturnTrimmedCombinedOutput()
+	commits, err := logCommits(repoDir, "--max-parents=0", "HEAD", "--")
 	if err != nil {
-		return Commit{}, errors.WithStack(fmt.Errorf("%s failed: %s", cmd.PrintableCommandArgs(), out))
+		return Commit{}, err
 	}
-	return parseCommit(out)
+	if len(commits) == 0 {
+		return Commit{}, errors.WithStack(fmt.Errorf("no root commit found"))
+	}
+	// the oldest root commit comes last
+	return commits[len(commits)-1], nil
 }
 
 // LastCommit ...
 func LastCommit(repoDir string) (Commit, error) {
-	cmd := command.New("git", "log", "-1", `--pretty=format:commit: %H%ndate: %ct%nauthor: %an%nmessage: %s`).SetDir(repoDir)
-	out, err := cmd.RunAndReturnTrimmedCombinedOutput()
-	if err != nil {
-		return Commit{}, errors.WithStack(fmt.Errorf("%s failed: %s", cmd.PrintableCommandArgs(), out))
-	}
-	return parseCommit(out)
+	return RevisionCommit(repoDir, "HEAD")
 }
 
-func parseCommitList(out string) ([]Commit, error) {
-	/*
-		commit: 7e429002426846bb0837cb8b06603eb47a17d846
-		date: 1523740143
-		author: Krisztián Gödrei
-		message: release wf (#2)
-	*/
-	var commits []Commit
-
-	hash := ""
-	dateStr := ""
-	author := ""
-	message := ""
-	messageStart := false
-	for _, line := range strings.Split(out, "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		if strings.HasPrefix(line, hashPrefix) {
-			if hash != "" {
-				date, err := parseDate(dateStr)
-				if err != nil {
-					return nil, err
-				}
-
-				commits = append(commits, Commit{
-					Hash:    hash,
-					Message: message,
-					Date:    date,
-					Author:  author,
-				})
-				message = ""
-				messageStart = false
-			}
-			hash = strings.TrimPrefix(line, hashPrefix)
-		} else if strings.HasPrefix(line, datePrefix) {
-			dateStr = strings.TrimPrefix(line, datePrefix)
-		} else if strings.HasPrefix(line, authorPrefix) {
-			author = strings.TrimPrefix(line, authorPrefix)
-		} else if strings.HasPrefix(line, messagePrefix) {
-			messageStart = true
-		}
+// CommitsTouchingPaths returns which of the given commits change files matching the include paths (every file if empty)
+// outside of the exclude paths. The paths are git pathspecs, like directories (app/) or globs (*.md).
+// Merge commits are compared to their first parent, so a merged pull request touches the paths changed by its commits.
+func CommitsTouchingPaths(repoDir string, hashes []string, includePaths, excludePaths []string) (map[string]bool, error) {
+	touching := map[string]bool{}
+	if len(hashes) == 0 {
+		return touching, nil
+	}
 
-		if messageStart {
-			if strings.HasPrefix(line, messagePrefix) {
-				message += strings.TrimPrefix(line, messagePrefix)
-			} else {
-				message += fmt.Sprintf("\n%s", line)
-			}
-		}
+	// git log would drop the merge commits by history simplification, diff-tree lists every commit with a matching change
+	args := []string{"diff-tree", "-r", "--stdin", "--root", "--diff-merges=first-parent", "--name-only", "--format=%x00%H", "--"}
+	args = append(args, includePaths...)
+	if len(includePaths) == 0 {
+		args = append(args, ".")
 	}
-	date, err := parseDate(dateStr)
-	if err != nil {
-		return nil, err
+	for _, path := range excludePaths {
+		args = append(args, ":(exclude)"+path)
 	}
 
-	commits = append(commits, Commit{
-		Hash:    hash,
-		Message: message,
-		Date:    date,
-		Author:  author,
-	})
-
-	return commits, nil
-}
-
-// Commits ...
-func Commits(repoDir string) ([]Commit, error) {
-	cmd := command.New("git", "log", "--no-merges", "--pretty=format:commit: %H%ndate: %ct%nauthor: %an%nmessage: %s").SetDir(repoDir)
+	cmd := command.New("git", args...).SetDir(repoDir).SetStdin(strings.NewReader(strings.Join(hashes, "\n") + "\n"))
 	out, err := cmd.RunAndReturnTrimmedCombinedOutput()
 	if err != nil {
 		return nil, errors.WithStack(fmt.Errorf("%s failed: %s", cmd.PrintableCommandArgs(), out))
 	}
 
-	commits, err := parseCommitList(out)
-	if err != nil {
-		return []Commit{}, err
+	// every commit is a NUL prefixed hash line followed by the changed files
+	for _, record := range strings.Split(out, "\x00")[1:] {
+		if fields := strings.Fields(record); len(fields) > 0 {
+			touching[fields[0]] = true
+		}
 	}
+	return touching, nil
+}
 
-	sort.Slice(commits, func(i, j int) bool {
-		return commits[i].Date.Before(commits[j].Date)
-	})
-
-	return commits, nil
+// Commits returns the commits reachable from toRevision but not from fromRevision,
+// the equivalent of `git log fromRevision..toRevision`, in the order of git log (newest first).
+// If fromRevision is empty, every commit reachable from toRevision is returned.
+// Use IterateCommits to process the commits without loading them into memory.
+func Commits(repoDir, fromRevision, toRevision string, opts LogOptions) ([]Commit, error) {
+	it, err := IterateCommits(repoDir, fromRevision, toRevision, opts)
+	if err != nil {
+		return nil, err
+	}
+	return collectCommits(it)
 }
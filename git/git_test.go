@@ -0,0 +1,259 @@
+package git
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/internal/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func commitMessages(commits []Commit) []string {
+	var messages []string
+	for _, commit := range commits {
+		messages = append(messages, commit.Message)
+	}
+	return messages
+}
+
+func TestCommits(t *testing.T) {
+	repo := gittest.New(t)
+	repo.Commit("initial", "2020-01-01T00:00:00Z")
+	repo.Git("tag", "1.0.0")
+	repo.Commit("feature", "2020-01-02T00:00:00Z")
+	repo.Git("checkout", "-q", "-b", "hotfix", "1.0.0")
+	// backdated commit, created before the tagged commit
+	repo.Commit("hotfix", "2019-01-01T00:00:00Z")
+	repo.Git("checkout", "-q", "-")
+
+	commits, err := Commits(repo.Dir, "1.0.0", "hotfix", LogOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"hotfix"}, commitMessages(commits))
+
+	commits, err = Commits(repo.Dir, "1.0.0", "HEAD", LogOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"feature"}, commitMessages(commits))
+
+	commits, err = Commits(repo.Dir, "", "hotfix", LogOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"hotfix", "initial"}, commitMessages(commits))
+
+	commits, err = Commits(repo.Dir, "HEAD", "HEAD", LogOptions{})
+	require.NoError(t, err)
+	require.Empty(t, commits)
+}
+
+func TestIsAncestor(t *testing.T) {
+	repo := gittest.New(t)
+	first := repo.Commit("initial", "2020-01-01T00:00:00Z")
+	second := repo.Commit("second", "2020-01-02T00:00:00Z")
+
+	isAncestor, err := IsAncestor(repo.Dir, first, second)
+	require.NoError(t, err)
+	require.True(t, isAncestor)
+
+	isAncestor, err = IsAncestor(repo.Dir, second, first)
+	require.NoError(t, err)
+	require.False(t, isAncestor)
+
+	_, err = IsAncestor(repo.Dir, "unknown", first)
+	require.Error(t, err)
+}
+
+func TestCommits_body(t *testing.T) {
+	repo := gittest.New(t)
+	repo.Commit("feat: add login\n\nExplain the change.\n\nSecond paragraph.\n\nSigned-off-by: Alice <alice@example.com>", "2020-01-01T00:00:00Z")
+
+	commits, err := Commits(repo.Dir, "", "HEAD", LogOptions{})
+	require.NoError(t, err)
+	require.Len(t, commits, 1)
+	require.Equal(t, "feat: add login", commits[0].Message)
+	require.Equal(t, "alice@example.com", commits[0].AuthorEmail)
+	require.Equal(t, "Explain the change.\n\nSecond paragraph.\n\nSigned-off-by: Alice <alice@example.com>", commits[0].Body)
+	require.Equal(t, []Trailer{{Key: "Signed-off-by", Value: "Alice <alice@example.com>"}}, commits[0].Trailers)
+	require.Equal(t, "Explain the change.\n\nSecond paragraph.", commits[0].BodyWithoutTrailers())
+}
+
+func TestTaggedCommits(t *testing.T) {
+	repo := gittest.New(t)
+	first := repo.Commit("initial", "2020-01-01T00:00:00Z")
+	repo.Git("tag", "1.0.0")
+	repo.Git("tag", "build-1")
+	second := repo.Commit("feature\n\nDetails.", "2020-01-02T00:00:00Z")
+	repo.GitWithEnv(nil, "tag", "-a", "1.1.0", "-m", "Release 1.1.0")
+	// tags of non-commit objects are not releases
+	repo.GitWithEnv(nil, "tag", "-a", "tree-tag", "-m", "tree", "HEAD^{tree}")
+
+	pattern, err := ParseTagPattern("")
+	require.NoError(t, err)
+
+	taggedCommits, err := TaggedCommits(repo.Dir, pattern)
+	require.NoError(t, err)
+	require.Len(t, taggedCommits, 2)
+
+	require.Equal(t, "1.0.0", taggedCommits[0].Tag)
+	require.Equal(t, first, taggedCommits[0].Hash)
+	require.Equal(t, "initial", taggedCommits[0].Message)
+
+	require.Equal(t, "1.1.0", taggedCommits[1].Tag)
+	require.Equal(t, second, taggedCommits[1].Hash)
+	require.Equal(t, "feature", taggedCommits[1].Message)
+	require.Equal(t, "Details.", taggedCommits[1].Body)
+	require.Equal(t, "Alice", taggedCommits[1].Author)
+	require.Equal(t, "alice@example.com", taggedCommits[1].AuthorEmail)
+	require.Equal(t, int64(1577923200), taggedCommits[1].Date.Unix())
+
+	pattern, err = ParseTagPattern("build-*")
+	require.NoError(t, err)
+
+	taggedCommits, err = TaggedCommits(repo.Dir, pattern)
+	require.NoError(t, err)
+	require.Len(t, taggedCommits, 1)
+	require.Equal(t, "build-1", taggedCommits[0].Tag)
+}
+
+func TestIterateCommits(t *testing.T) {
+	repo := gittest.New(t)
+	repo.Commit("first", "2020-01-01T00:00:00Z")
+	repo.Commit("second", "2020-01-02T00:00:00Z")
+	repo.Commit("third", "2020-01-03T00:00:00Z")
+
+	it, err := IterateCommits(repo.Dir, "HEAD~2", "HEAD", LogOptions{})
+	require.NoError(t, err)
+
+	commit, err := it.Next()
+	require.NoError(t, err)
+	require.Equal(t, "third", commit.Message)
+	commit, err = it.Next()
+	require.NoError(t, err)
+	require.Equal(t, "second", commit.Message)
+	_, err = it.Next()
+	require.Equal(t, io.EOF, err)
+	_, err = it.Next()
+	require.Equal(t, io.EOF, err)
+
+	// closing the iterator before reading every commit
+	it, err = IterateCommits(repo.Dir, "", "HEAD", LogOptions{})
+	require.NoError(t, err)
+	commit, err = it.Next()
+	require.NoError(t, err)
+	require.Equal(t, "third", commit.Message)
+	it.Close()
+	_, err = it.Next()
+	require.Equal(t, io.EOF, err)
+
+	it, err = IterateCommits(repo.Dir, "", "unknown-revision", LogOptions{})
+	require.NoError(t, err)
+	_, err = it.Next()
+	require.Error(t, err)
+	require.NotEqual(t, io.EOF, err)
+}
+
+func TestCommitsTouchingPaths(t *testing.T) {
+	repo := gittest.New(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(repo.Dir, "app"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(repo.Dir, "docs"), 0755))
+
+	commitFiles := func(message string, files ...string) string {
+		for _, file := range files {
+			require.NoError(t, os.WriteFile(filepath.Join(repo.Dir, file), []byte(message), 0644))
+		}
+		repo.Git("add", ".")
+		return repo.Commit(message, "2020-01-01T00:00:00Z")
+	}
+	app := commitFiles("app", "app/main.go")
+	docs := commitFiles("docs", "docs/README.md")
+	both := commitFiles("both", "app/main.go", "docs/README.md")
+	hashes := []string{app, docs, both}
+
+	touching, err := CommitsTouchingPaths(repo.Dir, hashes, []string{"app"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{app: true, both: true}, touching)
+
+	touching, err = CommitsTouchingPaths(repo.Dir, hashes, nil, []string{"docs"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{app: true, both: true}, touching)
+
+	touching, err = CommitsTouchingPaths(repo.Dir, hashes, []string{"docs"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{docs: true, both: true}, touching)
+
+	touching, err = CommitsTouchingPaths(repo.Dir, nil, []string{"docs"}, nil)
+	require.NoError(t, err)
+	require.Empty(t, touching)
+}
+
+func TestCommitsTouchingPaths_merge(t *testing.T) {
+	repo := gittest.New(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(repo.Dir, "app"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(repo.Dir, "docs"), 0755))
+	repo.Commit("initial", "2020-01-01T00:00:00Z")
+	repo.Git("branch", "-M", "main")
+
+	repo.Git("checkout", "-q", "-b", "feature")
+	require.NoError(t, os.WriteFile(filepath.Join(repo.Dir, "app", "main.go"), []byte("feature"), 0644))
+	repo.Git("add", ".")
+	feature := repo.Commit("feature work", "2020-01-02T00:00:00Z")
+
+	repo.Git("checkout", "-q", "main")
+	require.NoError(t, os.WriteFile(filepath.Join(repo.Dir, "docs", "README.md"), []byte("docs"), 0644))
+	repo.Git("add", ".")
+	docs := repo.Commit("docs", "2020-01-03T00:00:00Z")
+	repo.GitWithEnv([]string{"GIT_AUTHOR_DATE=2020-01-04T00:00:00Z", "GIT_COMMITTER_DATE=2020-01-04T00:00:00Z"},
+		"merge", "-q", "--no-ff", "feature", "-m", "Merge pull request #1 from octocat/feature")
+	merge := repo.Git("rev-parse", "HEAD")
+	hashes := []string{merge, docs, feature}
+
+	// the merge commit is compared to its first parent: it brings the app changes of the feature branch
+	touching, err := CommitsTouchingPaths(repo.Dir, hashes, []string{"app/"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{merge: true, feature: true}, touching)
+
+	touching, err = CommitsTouchingPaths(repo.Dir, []string{merge, docs}, []string{"docs/"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{docs: true}, touching)
+}
+
+func TestCommits_paths(t *testing.T) {
+	repo := gittest.New(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(repo.Dir, "ios"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(repo.Dir, "android"), 0755))
+
+	commitFile := func(message, file, date string) {
+		require.NoError(t, os.WriteFile(filepath.Join(repo.Dir, file), []byte(message), 0644))
+		repo.Git("add", ".")
+		repo.Commit(message, date)
+	}
+	commitFile("ios 1", "ios/App.swift", "2020-01-01T00:00:00Z")
+	commitFile("android 1", "android/App.kt", "2020-01-02T00:00:00Z")
+	commitFile("ios 2", "ios/App.swift", "2020-01-03T00:00:00Z")
+
+	commits, err := Commits(repo.Dir, "", "HEAD", LogOptions{Paths: []string{"ios"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"ios 2", "ios 1"}, commitMessages(commits))
+
+	commits, err = Commits(repo.Dir, "", "HEAD", LogOptions{Paths: []string{"android", "ios"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"ios 2", "android 1", "ios 1"}, commitMessages(commits))
+}
+
+func TestTaggedCommits_prefix(t *testing.T) {
+	repo := gittest.New(t)
+	repo.Commit("initial", "2020-01-01T00:00:00Z")
+	repo.Git("tag", "ios/v1.10.0")
+	repo.Git("tag", "android/v3.1.0")
+	repo.Commit("second", "2020-01-02T00:00:00Z")
+	repo.Git("tag", "ios/v1.9.0")
+	repo.Git("tag", "v2.0.0")
+
+	pattern, err := ParseTagPattern("")
+	require.NoError(t, err)
+
+	taggedCommits, err := TaggedCommits(repo.Dir, pattern.WithPrefix("ios/"))
+	require.NoError(t, err)
+	require.Len(t, taggedCommits, 2)
+	require.Equal(t, "ios/v1.9.0", taggedCommits[0].Tag)
+	require.Equal(t, "ios/v1.10.0", taggedCommits[1].Tag)
+}
@@ -0,0 +1,264 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/pkg/errors"
+)
+
+const (
+	// recordSeparator starts every record (commit or tag) of the git output.
+	recordSeparator = "\x1e"
+	// fieldTerminator terminates every field of a record. Git does not allow NUL in commit messages,
+	// so the fields can contain any other character, including new lines and record separators.
+	fieldTerminator = '\x00'
+
+	// logFormat lists the fields of a commit: hash, parent hashes, commit date, author date, author name, author email,
+	// committer name, committer email, subject and body.
+	logFormat       = "--format=%x1e%H%x00%P%x00%ct%x00%at%x00%an%x00%ae%x00%cn%x00%ce%x00%s%x00%b%x00"
+	logFormatFields = 10
+)
+
+func parseDate(unixTimeStampStr string) (time.Time, error) {
+	i, err := strconv.ParseInt(unixTimeStampStr, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if i < 0 {
+		return time.Time{}, errors.WithStack(fmt.Errorf("invalid time stamp (%s)", unixTimeStampStr))
+	}
+	return time.Unix(i, 0), nil
+}
+
+// recordReader reads the records of a git output, one record at a time.
+// Every record starts with recordSeparator and has a fixed number of fields terminated by fieldTerminator.
+type recordReader struct {
+	reader *bufio.Reader
+}
+
+func newRecordReader(r io.Reader) recordReader {
+	return recordReader{reader: bufio.NewReader(r)}
+}
+
+// next returns the fields of the next record, or io.EOF if there are no more records.
+func (r recordReader) next(fieldCount int) ([]string, error) {
+	fields := make([]string, fieldCount)
+	for i := range fields {
+		field, err := r.reader.ReadString(fieldTerminator)
+		if err == io.EOF {
+			if i == 0 && strings.Trim(field, "\n") == "" {
+				return nil, io.EOF
+			}
+			return nil, errors.WithStack(fmt.Errorf("unexpected end of record: %q", strings.Join(append(fields[:i], field), "\x00")))
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		fields[i] = strings.TrimSuffix(field, string(fieldTerminator))
+	}
+
+	// the records are separated by a new line
+	first := strings.TrimLeft(fields[0], "\n")
+	if !strings.HasPrefix(first, recordSeparator) {
+		return nil, errors.WithStack(fmt.Errorf("missing record separator before record: %q", first))
+	}
+	fields[0] = strings.TrimPrefix(first, recordSeparator)
+
+	return fields, nil
+}
+
+// commitFromFields creates a commit from the fields of logFormat.
+func commitFromFields(fields []string) (Commit, error) {
+	hash, parents, dateStr, authorDateStr := fields[0], fields[1], fields[2], fields[3]
+	author, authorEmail, committer, committerEmail := fields[4], fields[5], fields[6], fields[7]
+	subject, body := fields[8], fields[9]
+	if hash == "" {
+		return Commit{}, errors.WithStack(fmt.Errorf("missing commit hash"))
+	}
+
+	date, err := parseDate(dateStr)
+	if err != nil {
+		return Commit{}, err
+	}
+	authorDate, err := parseDate(authorDateStr)
+	if err != nil {
+		return Commit{}, err
+	}
+
+	// git terminates the body with a new line
+	commit := newCommit(hash, subject, strings.TrimRight(body, "\n"), date, author)
+	commit.Parents = strings.Fields(parents)
+	commit.AuthorDate = authorDate
+	// for-each-ref wraps the emails in angle brackets
+	commit.AuthorEmail = strings.Trim(authorEmail, "<>")
+	commit.Committer = committer
+	commit.CommitterEmail = strings.Trim(committerEmail, "<>")
+	return commit, nil
+}
+
+// commitParser parses the commits of a git log output written with logFormat, one commit at a time.
+type commitParser struct {
+	records recordReader
+}
+
+func newCommitParser(r io.Reader) commitParser {
+	return commitParser{records: newRecordReader(r)}
+}
+
+// Next returns the next commit of the log output, or io.EOF if there are no more commits.
+func (p commitParser) Next() (Commit, error) {
+	fields, err := p.records.next(logFormatFields)
+	if err != nil {
+		return Commit{}, err
+	}
+	return commitFromFields(fields)
+}
+
+func parseCommits(r io.Reader) ([]Commit, error) {
+	parser := newCommitParser(r)
+
+	var commits []Commit
+	for {
+		commit, err := parser.Next()
+		if err == io.EOF {
+			return commits, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, commit)
+	}
+}
+
+// gitOutput streams the standard output of a running git command.
+type gitOutput struct {
+	cmd    *command.Model
+	stdout io.Reader
+	stderr *bytes.Buffer
+}
+
+func startGit(repoDir string, args ...string) (gitOutput, error) {
+	stderr := &bytes.Buffer{}
+	cmd := command.New("git", args...).SetDir(repoDir).SetStderr(stderr)
+	stdout, err := cmd.GetCmd().StdoutPipe()
+	if err != nil {
+		return gitOutput{}, errors.WithStack(err)
+	}
+	if err := cmd.GetCmd().Start(); err != nil {
+		return gitOutput{}, errors.WithStack(fmt.Errorf("%s failed: %s", cmd.PrintableCommandArgs(), err))
+	}
+	return gitOutput{cmd: cmd, stdout: stdout, stderr: stderr}, nil
+}
+
+// wait waits for the command to exit, after its whole output is read.
+func (o gitOutput) wait() error {
+	if err := o.cmd.GetCmd().Wait(); err != nil {
+		return errors.WithStack(fmt.Errorf("%s failed: %s", o.cmd.PrintableCommandArgs(), strings.TrimSpace(o.stderr.String())))
+	}
+	return nil
+}
+
+// kill stops the command without reading the rest of its output.
+func (o gitOutput) kill() {
+	_ = o.cmd.GetCmd().Process.Kill()
+	_ = o.cmd.GetCmd().Wait()
+}
+
+// CommitIterator streams the commits listed by git log, without loading the whole list into memory.
+// The iterator has to be closed if it is not read till the end.
+type CommitIterator struct {
+	output gitOutput
+	parser commitParser
+	err    error
+}
+
+// LogOptions selects the commits of a revision range.
+type LogOptions struct {
+	// Merges is the listing mode of the merge commits, ExcludeMerges if empty.
+	Merges MergeMode
+	// Paths limits the commits to the ones touching the given paths (git pathspecs), every commit is listed if empty.
+	Paths []string
+}
+
+// IterateCommits streams the commits reachable from toRevision but not from fromRevision,
+// the equivalent of `git log fromRevision..toRevision`, newest first.
+// If fromRevision is empty, every commit reachable from toRevision is listed.
+func IterateCommits(repoDir, fromRevision, toRevision string, opts LogOptions) (*CommitIterator, error) {
+	revisionRange := toRevision
+	if fromRevision != "" {
+		revisionRange = fromRevision + ".." + toRevision
+	}
+
+	args := append(opts.Merges.logArgs(), revisionRange, "--")
+	args = append(args, opts.Paths...)
+	return newCommitIterator(repoDir, args...)
+}
+
+func newCommitIterator(repoDir string, args ...string) (*CommitIterator, error) {
+	output, err := startGit(repoDir, append([]string{"log", logFormat}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &CommitIterator{output: output, parser: newCommitParser(output.stdout)}, nil
+}
+
+// Next returns the next commit, or io.EOF if there are no more commits.
+func (it *CommitIterator) Next() (Commit, error) {
+	if it.err != nil {
+		return Commit{}, it.err
+	}
+
+	commit, err := it.parser.Next()
+	if err == io.EOF {
+		it.err = io.EOF
+		if err := it.output.wait(); err != nil {
+			it.err = err
+		}
+		return Commit{}, it.err
+	}
+	if err != nil {
+		it.err = errors.WithStack(fmt.Errorf("Failed to parse commits: %s", err))
+		it.output.kill()
+		return Commit{}, it.err
+	}
+
+	return commit, nil
+}
+
+// Close stops listing the commits.
+func (it *CommitIterator) Close() {
+	if it.err == nil {
+		it.err = io.EOF
+		it.output.kill()
+	}
+}
+
+// logCommits returns the commits listed by git log with the given arguments.
+func logCommits(repoDir string, args ...string) ([]Commit, error) {
+	it, err := newCommitIterator(repoDir, args...)
+	if err != nil {
+		return nil, err
+	}
+	return collectCommits(it)
+}
+
+func collectCommits(it *CommitIterator) ([]Commit, error) {
+	var commits []Commit
+	for {
+		commit, err := it.Next()
+		if err == io.EOF {
+			return commits, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, commit)
+	}
+}
@@ -0,0 +1,118 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/internal/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+// logRecord formats a commit the way git log writes it with logFormat.
+func logRecord(hash, parents, date, author, subject, body string) string {
+	email := strings.ToLower(strings.ReplaceAll(author, " ", ".")) + "@example.com"
+	if body != "" {
+		body += "\n"
+	}
+	return strings.Join([]string{recordSeparator + hash, parents, date, date, author, email, "GitHub", "noreply@github.com", subject, body}, "\x00") + "\x00\n"
+}
+
+func TestParseCommits(t *testing.T) {
+	out := logRecord("2222", "1111 0000", "1455788198", "Bitrise Developer", "commit: 1111", "date: 1\n\n\nauthor: x\nmessage: y\x1e\n\nSigned-off-by: Bob <bob@example.com>") +
+		logRecord("1111", "", "1455631980", "Bitrise Bot", "Merge branch 'master'", "")
+
+	commits, err := parseCommits(strings.NewReader(out))
+	require.NoError(t, err)
+	require.Len(t, commits, 2)
+
+	require.Equal(t, "2222", commits[0].Hash)
+	require.Equal(t, []string{"1111", "0000"}, commits[0].Parents)
+	require.True(t, commits[0].IsMerge())
+	require.Equal(t, time.Unix(1455788198, 0), commits[0].Date)
+	require.Equal(t, "Bitrise Developer", commits[0].Author)
+	require.Equal(t, "bitrise.developer@example.com", commits[0].AuthorEmail)
+	require.Equal(t, "commit: 1111", commits[0].Message)
+	require.Equal(t, "date: 1\n\n\nauthor: x\nmessage: y\x1e\n\nSigned-off-by: Bob <bob@example.com>", commits[0].Body)
+	require.Equal(t, []Trailer{{Key: "Signed-off-by", Value: "Bob <bob@example.com>"}}, commits[0].Trailers)
+
+	require.Equal(t, "1111", commits[1].Hash)
+	require.Empty(t, commits[1].Parents)
+	require.False(t, commits[1].IsMerge())
+	require.Equal(t, "Merge branch 'master'", commits[1].Message)
+	require.Equal(t, "", commits[1].Body)
+}
+
+func TestParseCommits_empty(t *testing.T) {
+	commits, err := parseCommits(strings.NewReader(""))
+	require.NoError(t, err)
+	require.Nil(t, commits)
+}
+
+func TestParseCommits_invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+	}{
+		{name: "truncated commit", out: recordSeparator + "1111\x00\x001455631980\x001455631980\x00Bitrise Bot\x00bot@example.com\x00GitHub\x00noreply@github.com\x00"},
+		{name: "missing record separator", out: "1111\x00\x001455631980\x001455631980\x00Bitrise Bot\x00bot@example.com\x00GitHub\x00noreply@github.com\x00subject\x00\x00"},
+		{name: "missing hash", out: recordSeparator + "\x00\x001455631980\x001455631980\x00Bitrise Bot\x00bot@example.com\x00GitHub\x00noreply@github.com\x00subject\x00\x00"},
+		{name: "invalid date", out: recordSeparator + "1111\x00\x00yesterday\x001455631980\x00Bitrise Bot\x00bot@example.com\x00GitHub\x00noreply@github.com\x00subject\x00\x00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseCommits(strings.NewReader(tt.out))
+			require.Error(t, err)
+		})
+	}
+}
+
+func FuzzParseCommits(f *testing.F) {
+	f.Add("Alice", "Add login", "")
+	f.Add("Krisztián Gödrei", "feat(ui)!: add login", "Details.\n\nBREAKING CHANGE: removed the old login")
+	f.Add("commit: 1111", "date: 1455631980", "author: Bob\nmessage: x\nbody: y")
+	f.Add("\x1e", "\x1e\n\x1e", "\n\n\n\x1e\n\n")
+
+	f.Fuzz(func(t *testing.T, author, subject, body string) {
+		if strings.ContainsRune(author+subject+body, 0) {
+			// git does not allow NUL in commit messages
+			t.Skip()
+		}
+
+		out := logRecord("2222", "1111", "1455788198", author, subject, body) + logRecord("1111", "", "1455631980", author, subject, body)
+		commits, err := parseCommits(strings.NewReader(out))
+		require.NoError(t, err)
+		require.Len(t, commits, 2)
+
+		for i, hash := range []string{"2222", "1111"} {
+			require.Equal(t, hash, commits[i].Hash)
+			require.Equal(t, author, commits[i].Author)
+			require.Equal(t, subject, commits[i].Message)
+			require.Equal(t, strings.TrimRight(body, "\n"), commits[i].Body)
+		}
+	})
+}
+
+func TestCommits_messageContent(t *testing.T) {
+	repo := gittest.New(t)
+	messages := []string{
+		"commit: 1111\n\ndate: 1455631980\nauthor: Bob\n\n\n\nmessage: \x1e\nbody: fake",
+		"Krisztián Gödrei 🚀\n\n  indented\n\n\n\nlast line",
+	}
+	for i, message := range messages {
+		date := fmt.Sprintf("2020-01-0%dT00:00:00Z", i+1)
+		repo.GitWithEnv([]string{"GIT_AUTHOR_DATE=" + date, "GIT_COMMITTER_DATE=" + date},
+			"commit", "-q", "--allow-empty", "--cleanup=verbatim", "-m", message)
+	}
+
+	commits, err := Commits(repo.Dir, "", "HEAD", LogOptions{})
+	require.NoError(t, err)
+	require.Len(t, commits, 2)
+	// newest first
+	for i, message := range messages {
+		subject, body, _ := strings.Cut(message, "\n\n")
+		require.Equal(t, subject, commits[len(commits)-1-i].Message)
+		require.Equal(t, body, commits[len(commits)-1-i].Body)
+	}
+}
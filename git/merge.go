@@ -0,0 +1,121 @@
+package git
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MergeMode selects how the merge commits are listed.
+type MergeMode string
+
+// MergeModes ...
+const (
+	// ExcludeMerges lists the non-merge commits.
+	ExcludeMerges MergeMode = "exclude"
+	// IncludeMerges lists both the merge and the non-merge commits.
+	IncludeMerges MergeMode = "include"
+	// OnlyMerges lists the merge commits.
+	OnlyMerges MergeMode = "only"
+	// FirstParent lists the commits of the first-parent history: one commit per merged pull request,
+	// and the commits made directly on the branch.
+	FirstParent MergeMode = "first-parent"
+)
+
+func (m MergeMode) logArgs() []string {
+	switch m {
+	case IncludeMerges:
+		return nil
+	case OnlyMerges:
+		return []string{"--merges"}
+	case FirstParent:
+		return []string{"--first-parent"}
+	default:
+		return []string{"--no-merges"}
+	}
+}
+
+// PullRequest is the pull request (merge request on GitLab) merged by a commit,
+// either by a merge commit or by a squash merge.
+type PullRequest struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Branch string `json:"branch,omitempty"`
+	URL    string `json:"url,omitempty"`
+}
+
+var (
+	// GitHub: Merge pull request #123 from owner/branch
+	githubMergeRegexp = regexp.MustCompile(`^Merge pull request #(\d+) from (\S+)`)
+	// Bitbucket Server: Merge pull request #123 in PROJECT/repo from branch to main
+	bitbucketServerMergeRegexp = regexp.MustCompile(`^Merge pull request #(\d+) in \S+ from (\S+) to \S+`)
+	// Bitbucket Cloud: Merged in branch (pull request #123)
+	bitbucketCloudMergeRegexp = regexp.MustCompile(`^Merged in (\S+) \(pull request #(\d+)\)`)
+	// GitLab: Merge branch 'branch' into 'main', with a "See merge request group/project!123" line in the body
+	gitlabMergeRegexp        = regexp.MustCompile(`^Merge branch '([^']+)' into '[^']+'`)
+	gitlabMergeRequestRegexp = regexp.MustCompile(`(?m)^See merge request \S*!(\d+)\s*$`)
+	// squash merge: Add login screen (#123)
+	squashMergeRegexp = regexp.MustCompile(`^(.*\S)\s+\(#(\d+)\)$`)
+)
+
+// parsePullRequest parses the pull request merged by a commit from the GitHub, GitLab and Bitbucket merge commit messages,
+// or from the pull request number suffix of squash merged commits.
+// The title of a merged pull request is the first paragraph of the commit body, or the merged branch if the body has no title.
+func parsePullRequest(subject, body string) (PullRequest, bool) {
+	subject = strings.TrimSpace(subject)
+	if pr, ok := parseMergePullRequest(subject, body); ok {
+		return pr, true
+	}
+
+	match := squashMergeRegexp.FindStringSubmatch(subject)
+	if match == nil {
+		return PullRequest{}, false
+	}
+	n, err := strconv.Atoi(match[2])
+	if err != nil {
+		return PullRequest{}, false
+	}
+	return PullRequest{Number: n, Title: match[1]}, true
+}
+
+func parseMergePullRequest(subject, body string) (PullRequest, bool) {
+	var number, branch string
+	if match := bitbucketServerMergeRegexp.FindStringSubmatch(subject); match != nil {
+		number, branch = match[1], match[2]
+	} else if match := githubMergeRegexp.FindStringSubmatch(subject); match != nil {
+		number, branch = match[1], match[2]
+	} else if match := bitbucketCloudMergeRegexp.FindStringSubmatch(subject); match != nil {
+		number, branch = match[2], match[1]
+	} else if match := gitlabMergeRegexp.FindStringSubmatch(subject); match != nil {
+		mergeRequest := gitlabMergeRequestRegexp.FindStringSubmatch(body)
+		if mergeRequest == nil {
+			return PullRequest{}, false
+		}
+		number, branch = mergeRequest[1], match[1]
+	} else {
+		return PullRequest{}, false
+	}
+
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return PullRequest{}, false
+	}
+
+	title := pullRequestTitle(body)
+	if title == "" {
+		title = branch
+	}
+
+	return PullRequest{Number: n, Title: title, Branch: branch}, true
+}
+
+// pullRequestTitle returns the first paragraph of the merge commit body, unless it is generated by the hosting provider.
+func pullRequestTitle(body string) string {
+	body = strings.TrimSpace(strings.ReplaceAll(body, "\r\n", "\n"))
+	paragraph := strings.Split(body, "\n\n")[0]
+	if strings.HasPrefix(paragraph, "* commit '") || gitlabMergeRequestRegexp.MatchString(paragraph) {
+		// Bitbucket Server lists the merged commits, GitLab adds the merge request reference
+		return ""
+	}
+	return strings.Join(strings.Fields(paragraph), " ")
+}
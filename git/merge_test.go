@@ -0,0 +1,135 @@
+package git
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/internal/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parsePullRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		body    string
+		want    PullRequest
+		wantOk  bool
+	}{
+		{
+			name:    "GitHub",
+			subject: "Merge pull request #42 from octocat/feature/login",
+			body:    "Add login screen",
+			want:    PullRequest{Number: 42, Title: "Add login screen", Branch: "octocat/feature/login"},
+			wantOk:  true,
+		},
+		{
+			name:    "GitHub without title",
+			subject: "Merge pull request #42 from octocat/feature/login",
+			want:    PullRequest{Number: 42, Title: "octocat/feature/login", Branch: "octocat/feature/login"},
+			wantOk:  true,
+		},
+		{
+			name:    "GitLab",
+			subject: "Merge branch 'feature/login' into 'main'",
+			body:    "Add login screen\n\nCloses #12\n\nSee merge request group/project!7",
+			want:    PullRequest{Number: 7, Title: "Add login screen", Branch: "feature/login"},
+			wantOk:  true,
+		},
+		{
+			name:    "GitLab without title",
+			subject: "Merge branch 'feature/login' into 'main'",
+			body:    "See merge request group/project!7",
+			want:    PullRequest{Number: 7, Title: "feature/login", Branch: "feature/login"},
+			wantOk:  true,
+		},
+		{
+			name:    "GitLab branch merge",
+			subject: "Merge branch 'feature/login' into 'main'",
+			wantOk:  false,
+		},
+		{
+			name:    "Bitbucket Cloud",
+			subject: "Merged in feature/login (pull request #5)",
+			body:    "Add login screen\n\n* Add login form\n\nApproved-by: Bob",
+			want:    PullRequest{Number: 5, Title: "Add login screen", Branch: "feature/login"},
+			wantOk:  true,
+		},
+		{
+			name:    "Bitbucket Server",
+			subject: "Merge pull request #3 in PROJ/app from feature/login to master",
+			body:    "* commit '1111111':\n  Add login form",
+			want:    PullRequest{Number: 3, Title: "feature/login", Branch: "feature/login"},
+			wantOk:  true,
+		},
+		{
+			name:    "squash merge",
+			subject: "Tool-248 firebase (#7)",
+			want:    PullRequest{Number: 7, Title: "Tool-248 firebase"},
+			wantOk:  true,
+		},
+		{
+			name:    "issue reference",
+			subject: "Fix crash (#7) on launch",
+			wantOk:  false,
+		},
+		{
+			name:    "not a pull request",
+			subject: "Add login screen",
+			wantOk:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parsePullRequest(tt.subject, tt.body)
+			require.Equal(t, tt.wantOk, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_newCommit_pullRequest(t *testing.T) {
+	commit := newCommit("hash", "Merge pull request #42 from octocat/login", "feat(ui): add login screen", time.Unix(0, 0), "Alice")
+	require.Equal(t, &PullRequest{Number: 42, Title: "feat(ui): add login screen", Branch: "octocat/login"}, commit.PullRequest)
+	require.Equal(t, ConventionalCommit{Type: "feat", Scope: "ui", Description: "add login screen"}, commit.ConventionalCommit)
+}
+
+func Test_newCommit_squashMerge(t *testing.T) {
+	commit := newCommit("hash", "feat(ui): add login screen (#142)", "", time.Unix(0, 0), "Alice")
+	require.Equal(t, &PullRequest{Number: 142, Title: "feat(ui): add login screen"}, commit.PullRequest)
+	require.Equal(t, ConventionalCommit{Type: "feat", Scope: "ui", Description: "add login screen"}, commit.ConventionalCommit)
+}
+
+func TestCommits_mergeModes(t *testing.T) {
+	repo := gittest.New(t)
+	repo.Commit("initial", "2020-01-01T00:00:00Z")
+	repo.Git("checkout", "-q", "-b", "feature")
+	repo.Commit("feature work", "2020-01-02T00:00:00Z")
+	repo.Git("checkout", "-q", "-")
+	repo.Commit("direct", "2020-01-03T00:00:00Z")
+	repo.GitWithEnv([]string{"GIT_AUTHOR_DATE=2020-01-04T00:00:00Z", "GIT_COMMITTER_DATE=2020-01-04T00:00:00Z"},
+		"merge", "-q", "--no-ff", "feature", "-m", "Merge pull request #1 from octocat/feature", "-m", "Add feature")
+
+	tests := []struct {
+		mode MergeMode
+		want []string
+	}{
+		{mode: "", want: []string{"direct", "feature work"}},
+		{mode: ExcludeMerges, want: []string{"direct", "feature work"}},
+		{mode: IncludeMerges, want: []string{"Merge pull request #1 from octocat/feature", "direct", "feature work"}},
+		{mode: OnlyMerges, want: []string{"Merge pull request #1 from octocat/feature"}},
+		{mode: FirstParent, want: []string{"Merge pull request #1 from octocat/feature", "direct"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			commits, err := Commits(repo.Dir, "HEAD~2", "HEAD", LogOptions{Merges: tt.mode})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, commitMessages(commits))
+		})
+	}
+
+	commits, err := Commits(repo.Dir, "HEAD~2", "HEAD", LogOptions{Merges: OnlyMerges})
+	require.NoError(t, err)
+	require.True(t, commits[0].IsMerge())
+	require.Equal(t, &PullRequest{Number: 1, Title: "Add feature", Branch: "octocat/feature"}, commits[0].PullRequest)
+}
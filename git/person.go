@@ -0,0 +1,85 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/pkg/errors"
+)
+
+// Person is an author, committer or co-author of a commit.
+type Person struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+// ParsePerson parses a `Name <email>` contact, like the value of a Co-authored-by trailer.
+func ParsePerson(contact string) Person {
+	contact = strings.TrimSpace(contact)
+	start, end := strings.LastIndex(contact, "<"), strings.LastIndex(contact, ">")
+	if start == -1 || end < start {
+		return Person{Name: contact}
+	}
+	return Person{
+		Name:  strings.TrimSpace(contact[:start]),
+		Email: strings.TrimSpace(contact[start+1 : end]),
+	}
+}
+
+// String returns the `Name <email>` contact of the person.
+func (p Person) String() string {
+	if p.Email == "" {
+		return p.Name
+	}
+	if p.Name == "" {
+		return "<" + p.Email + ">"
+	}
+	return p.Name + " <" + p.Email + ">"
+}
+
+// mailmapBatchSize limits the number of contacts passed to a single git check-mailmap call.
+const mailmapBatchSize = 100
+
+// CheckMailmap returns the canonical name and email of the people according to the .mailmap file of the repository.
+// People without an email are returned unchanged.
+func CheckMailmap(repoDir string, people []Person) ([]Person, error) {
+	mapped := make([]Person, len(people))
+	copy(mapped, people)
+
+	var indexes []int
+	for i, person := range people {
+		if person.Email != "" {
+			indexes = append(indexes, i)
+		}
+	}
+
+	for len(indexes) > 0 {
+		batch := indexes
+		if len(batch) > mailmapBatchSize {
+			batch = batch[:mailmapBatchSize]
+		}
+		indexes = indexes[len(batch):]
+
+		args := []string{"check-mailmap"}
+		for _, i := range batch {
+			args = append(args, people[i].String())
+		}
+
+		cmd := command.New("git", args...).SetDir(repoDir)
+		out, err := cmd.RunAndReturnTrimmedCombinedOutput()
+		if err != nil {
+			return nil, errors.WithStack(fmt.Errorf("%s failed: %s", cmd.PrintableCommandArgs(), out))
+		}
+
+		lines := strings.Split(out, "\n")
+		if len(lines) != len(batch) {
+			return nil, errors.WithStack(fmt.Errorf("unexpected git check-mailmap output: %s", out))
+		}
+		for j, i := range batch {
+			mapped[i] = ParsePerson(lines[j])
+		}
+	}
+
+	return mapped, nil
+}
@@ -0,0 +1,84 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/internal/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePerson(t *testing.T) {
+	tests := []struct {
+		contact string
+		want    Person
+	}{
+		{contact: "Bob <bob@example.com>", want: Person{Name: "Bob", Email: "bob@example.com"}},
+		{contact: "  Krisztián Gödrei  <k@example.com> ", want: Person{Name: "Krisztián Gödrei", Email: "k@example.com"}},
+		{contact: "<bob@example.com>", want: Person{Email: "bob@example.com"}},
+		{contact: "Bob", want: Person{Name: "Bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.contact, func(t *testing.T) {
+			got := ParsePerson(tt.contact)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, ParsePerson(got.String()), got)
+		})
+	}
+}
+
+func Test_newCommit_coAuthors(t *testing.T) {
+	body := "Pair programming.\n\nCo-authored-by: Bob <bob@example.com>\nco-authored-by: Carol <carol@example.com>"
+	commit := newCommit("hash", "Add login", body, time.Unix(0, 0), "Alice")
+	commit.AuthorEmail = "alice@example.com"
+
+	require.Equal(t, []Person{{Name: "Bob", Email: "bob@example.com"}, {Name: "Carol", Email: "carol@example.com"}}, commit.CoAuthors)
+	require.Equal(t, []Person{
+		{Name: "Alice", Email: "alice@example.com"},
+		{Name: "Bob", Email: "bob@example.com"},
+		{Name: "Carol", Email: "carol@example.com"},
+	}, commit.Contributors())
+}
+
+func TestCommits_committer(t *testing.T) {
+	repo := gittest.New(t)
+	repo.GitWithEnv([]string{
+		"GIT_AUTHOR_DATE=2020-01-01T00:00:00Z", "GIT_COMMITTER_DATE=2020-01-02T00:00:00Z",
+		"GIT_COMMITTER_NAME=GitHub", "GIT_COMMITTER_EMAIL=noreply@github.com",
+	}, "commit", "-q", "--allow-empty", "-m", "rebased")
+
+	commits, err := Commits(repo.Dir, "", "HEAD", LogOptions{})
+	require.NoError(t, err)
+	require.Len(t, commits, 1)
+	require.Equal(t, "Alice", commits[0].Author)
+	require.Equal(t, "alice@example.com", commits[0].AuthorEmail)
+	require.Equal(t, "GitHub", commits[0].Committer)
+	require.Equal(t, "noreply@github.com", commits[0].CommitterEmail)
+	require.Equal(t, int64(1577836800), commits[0].AuthorDate.Unix())
+	require.Equal(t, int64(1577923200), commits[0].Date.Unix())
+}
+
+func TestCheckMailmap(t *testing.T) {
+	repo := gittest.New(t)
+	require.NoError(t, os.WriteFile(filepath.Join(repo.Dir, ".mailmap"), []byte("Alice Smith <alice@example.com> <alice@old.example.com>\n"), 0644))
+
+	people := []Person{
+		{Name: "alice", Email: "alice@old.example.com"},
+		{Name: "Bob", Email: "bob@example.com"},
+		{Name: "No Email"},
+	}
+	mapped, err := CheckMailmap(repo.Dir, people)
+	require.NoError(t, err)
+	require.Equal(t, []Person{
+		{Name: "Alice Smith", Email: "alice@example.com"},
+		{Name: "Bob", Email: "bob@example.com"},
+		{Name: "No Email"},
+	}, mapped)
+	require.Equal(t, "alice", people[0].Name)
+
+	mapped, err = CheckMailmap(repo.Dir, nil)
+	require.NoError(t, err)
+	require.Empty(t, mapped)
+}
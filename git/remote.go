@@ -0,0 +1,172 @@
+package git
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/pkg/errors"
+)
+
+// Hosting providers of the repositories.
+const (
+	GitHub      = "github"
+	GitLab      = "gitlab"
+	Bitbucket   = "bitbucket"
+	AzureDevOps = "azure"
+)
+
+// scp-like SSH remote: [user@]host:path
+var scpLikeRemoteRegexp = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+
+var shaRegexp = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// Repository is the web interface of a hosted repository.
+type Repository struct {
+	Provider string
+	// URL is the web URL of the repository, like https://github.com/owner/repo
+	URL string
+}
+
+// RemoteURL returns the URL of the given remote.
+func RemoteURL(repoDir, remote string) (string, error) {
+	cmd := command.New("git", "remote", "get-url", remote).SetDir(repoDir)
+	out, err := cmd.RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		return "", errors.WithStack(fmt.Errorf("%s failed: %s", cmd.PrintableCommandArgs(), out))
+	}
+	return out, nil
+}
+
+// ParseRepository returns the web interface of the repository from its clone URL (SSH or HTTPS).
+// If provider is empty, it is detected from the host of the URL.
+func ParseRepository(remoteURL, provider string) (Repository, error) {
+	host, repoPath, err := splitRemoteURL(remoteURL)
+	if err != nil {
+		return Repository{}, err
+	}
+
+	if provider == "" {
+		provider = detectProvider(host)
+		if provider == "" {
+			return Repository{}, fmt.Errorf("unable to detect the hosting provider of the repository (%s)", remoteURL)
+		}
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	if provider == AzureDevOps {
+		host, repoPath = azureDevOpsWebPath(host, repoPath)
+	}
+
+	return Repository{Provider: provider, URL: "https://" + host + "/" + repoPath}, nil
+}
+
+func splitRemoteURL(remoteURL string) (string, string, error) {
+	remoteURL = strings.TrimSpace(remoteURL)
+	if !strings.Contains(remoteURL, "://") {
+		match := scpLikeRemoteRegexp.FindStringSubmatch(remoteURL)
+		if match == nil {
+			return "", "", fmt.Errorf("invalid repository URL: %s", remoteURL)
+		}
+		return match[1], match[2], nil
+	}
+
+	u, err := url.Parse(remoteURL)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid repository URL (%s): %s", remoteURL, err)
+	}
+	if u.Hostname() == "" || strings.Trim(u.Path, "/") == "" {
+		return "", "", fmt.Errorf("invalid repository URL: %s", remoteURL)
+	}
+
+	host := u.Hostname()
+	if u.Scheme == "http" || u.Scheme == "https" {
+		host = u.Host
+	}
+	return host, u.Path, nil
+}
+
+func detectProvider(host string) string {
+	host = strings.ToLower(host)
+	switch {
+	case strings.Contains(host, "github"):
+		return GitHub
+	case strings.Contains(host, "gitlab"):
+		return GitLab
+	case strings.Contains(host, "bitbucket"):
+		return Bitbucket
+	case strings.HasSuffix(host, "dev.azure.com"), strings.HasSuffix(host, "visualstudio.com"):
+		return AzureDevOps
+	}
+	return ""
+}
+
+// azureDevOpsWebPath converts the SSH path (v3/org/project/repo) to the web path (org/project/_git/repo).
+func azureDevOpsWebPath(host, repoPath string) (string, string) {
+	host = strings.TrimPrefix(host, "ssh.")
+	if strings.HasPrefix(repoPath, "v3/") {
+		split := strings.Split(strings.TrimPrefix(repoPath, "v3/"), "/")
+		if len(split) == 3 {
+			repoPath = split[0] + "/" + split[1] + "/_git/" + split[2]
+		}
+	}
+	return host, repoPath
+}
+
+// CommitURL returns the web URL of the commit.
+func (r Repository) CommitURL(hash string) string {
+	switch r.Provider {
+	case GitHub, AzureDevOps:
+		return r.URL + "/commit/" + hash
+	case GitLab:
+		return r.URL + "/-/commit/" + hash
+	case Bitbucket:
+		return r.URL + "/commits/" + hash
+	}
+	return ""
+}
+
+// PullRequestURL returns the web URL of the pull request (merge request on GitLab) with the given number.
+func (r Repository) PullRequestURL(number int) string {
+	n := strconv.Itoa(number)
+	switch r.Provider {
+	case GitHub:
+		return r.URL + "/pull/" + n
+	case GitLab:
+		return r.URL + "/-/merge_requests/" + n
+	case Bitbucket:
+		return r.URL + "/pull-requests/" + n
+	case AzureDevOps:
+		return r.URL + "/pullrequest/" + n
+	}
+	return ""
+}
+
+// CompareURL returns the web URL of the diff between the from and to revisions (tags or commit hashes).
+func (r Repository) CompareURL(from, to string) string {
+	if from == "" || to == "" {
+		return ""
+	}
+
+	switch r.Provider {
+	case GitHub:
+		return r.URL + "/compare/" + from + "..." + to
+	case GitLab:
+		return r.URL + "/-/compare/" + from + "..." + to
+	case Bitbucket:
+		return r.URL + "/branches/compare/" + to + "%0D" + from
+	case AzureDevOps:
+		return r.URL + "/branchCompare?baseVersion=" + azureDevOpsVersion(from) + "&targetVersion=" + azureDevOpsVersion(to)
+	}
+	return ""
+}
+
+func azureDevOpsVersion(revision string) string {
+	if shaRegexp.MatchString(revision) {
+		return "GC" + revision
+	}
+	return "GT" + url.QueryEscape(revision)
+}
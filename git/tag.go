@@ -0,0 +1,115 @@
+package git
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/semver"
+)
+
+// TagPattern selects the release tags by a glob (v*) or by a regular expression wrapped in slashes (/^v\d+/).
+// The empty pattern matches every tag.
+// If the pattern has a prefix (ios/), only the tags with the prefix are selected,
+// and the pattern and the semantic version are matched against the rest of the tag (ios/v1.2.0 is version v1.2.0).
+type TagPattern struct {
+	glob   string
+	regexp *regexp.Regexp
+	prefix string
+}
+
+// ParseTagPattern ...
+func ParseTagPattern(pattern string) (TagPattern, error) {
+	pattern = strings.TrimSpace(pattern)
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return TagPattern{}, fmt.Errorf("invalid tag pattern regular expression (%s): %s", pattern, err)
+		}
+		return TagPattern{regexp: re}, nil
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return TagPattern{}, fmt.Errorf("invalid tag pattern glob (%s): %s", pattern, err)
+	}
+	return TagPattern{glob: pattern}, nil
+}
+
+// WithPrefix returns the pattern scoped to the tags with the given prefix.
+func (p TagPattern) WithPrefix(prefix string) TagPattern {
+	p.prefix = prefix
+	return p
+}
+
+// Prefix returns the tag prefix of the pattern.
+func (p TagPattern) Prefix() string {
+	return p.prefix
+}
+
+// Match reports whether the tag matches the pattern.
+func (p TagPattern) Match(tag string) bool {
+	if !strings.HasPrefix(tag, p.prefix) {
+		return false
+	}
+	tag = strings.TrimPrefix(tag, p.prefix)
+
+	if p.regexp != nil {
+		return p.regexp.MatchString(tag)
+	}
+	if p.glob == "" {
+		return true
+	}
+	match, err := path.Match(p.glob, tag)
+	return err == nil && match
+}
+
+// Version parses the semantic version of the tag, without the prefix of the pattern.
+func (p TagPattern) Version(tag string) (semver.Version, error) {
+	return semver.Parse(strings.TrimPrefix(tag, p.prefix))
+}
+
+// IsPreRelease reports whether the tag is a semantic version with pre-release identifiers (ios/1.0.0-rc.1),
+// without the prefix of the pattern.
+func (p TagPattern) IsPreRelease(tag string) bool {
+	v, err := p.Version(tag)
+	return err == nil && v.IsPreRelease()
+}
+
+// sortTaggedCommits orders the tagged commits by semantic version precedence, lowest first.
+// If none of the tags are semantic versions, the tagged commits are ordered by date,
+// otherwise tags which are not semantic versions are left out.
+func sortTaggedCommits(taggedCommits []Commit, pattern TagPattern) []Commit {
+	type versionedCommit struct {
+		commit  Commit
+		version semver.Version
+	}
+
+	var versioned []versionedCommit
+	for _, commit := range taggedCommits {
+		if v, err := pattern.Version(commit.Tag); err == nil {
+			versioned = append(versioned, versionedCommit{commit: commit, version: v})
+		}
+	}
+
+	if len(versioned) == 0 {
+		sort.SliceStable(taggedCommits, func(i, j int) bool {
+			return taggedCommits[i].Date.Before(taggedCommits[j].Date)
+		})
+		return taggedCommits
+	}
+
+	sort.SliceStable(versioned, func(i, j int) bool {
+		if c := versioned[i].version.Compare(versioned[j].version); c != 0 {
+			return c < 0
+		}
+		return versioned[i].commit.Date.Before(versioned[j].commit.Date)
+	})
+
+	sorted := make([]Commit, 0, len(versioned))
+	for _, v := range versioned {
+		sorted = append(sorted, v.commit)
+	}
+	return sorted
+}
@@ -0,0 +1,84 @@
+package git
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagPattern_Match(t *testing.T) {
+	tests := []struct {
+		pattern string
+		tag     string
+		want    bool
+	}{
+		{pattern: "", tag: "build-123", want: true},
+		{pattern: "v*", tag: "v1.0.0", want: true},
+		{pattern: "v*", tag: "build-123", want: false},
+		{pattern: `/^\d+\.\d+\.\d+$/`, tag: "1.0.0", want: true},
+		{pattern: `/^\d+\.\d+\.\d+$/`, tag: "android-1.0.0", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.tag, func(t *testing.T) {
+			pattern, err := ParseTagPattern(tt.pattern)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, pattern.Match(tt.tag))
+		})
+	}
+
+	_, err := ParseTagPattern("/[/")
+	require.Error(t, err)
+	_, err = ParseTagPattern("[")
+	require.Error(t, err)
+}
+
+func Test_sortTaggedCommits(t *testing.T) {
+	tagsOf := func(commits []Commit) []string {
+		var tags []string
+		for _, commit := range commits {
+			tags = append(tags, commit.Tag)
+		}
+		return tags
+	}
+
+	sameDate := time.Unix(100, 0)
+	sorted := sortTaggedCommits([]Commit{
+		{Tag: "1.10.0", Date: time.Unix(50, 0)},
+		{Tag: "build-123", Date: sameDate},
+		{Tag: "v1.2.0", Date: sameDate},
+		{Tag: "1.2.0-rc.1", Date: sameDate},
+		{Tag: "1.9.0", Date: time.Unix(200, 0)},
+	}, TagPattern{})
+	require.Equal(t, []string{"1.2.0-rc.1", "v1.2.0", "1.9.0", "1.10.0"}, tagsOf(sorted))
+
+	sorted = sortTaggedCommits([]Commit{
+		{Tag: "build-2", Date: time.Unix(200, 0)},
+		{Tag: "build-1", Date: time.Unix(100, 0)},
+	}, TagPattern{})
+	require.Equal(t, []string{"build-1", "build-2"}, tagsOf(sorted))
+
+	sorted = sortTaggedCommits([]Commit{
+		{Tag: "ios/v1.10.0", Date: time.Unix(100, 0)},
+		{Tag: "ios/v1.9.0", Date: time.Unix(200, 0)},
+	}, TagPattern{}.WithPrefix("ios/"))
+	require.Equal(t, []string{"ios/v1.9.0", "ios/v1.10.0"}, tagsOf(sorted))
+}
+
+func TestTagPattern_WithPrefix(t *testing.T) {
+	pattern, err := ParseTagPattern("v*")
+	require.NoError(t, err)
+	pattern = pattern.WithPrefix("ios/")
+
+	require.True(t, pattern.Match("ios/v1.2.0"))
+	require.False(t, pattern.Match("android/v1.2.0"))
+	require.False(t, pattern.Match("v1.2.0"))
+	require.False(t, pattern.Match("ios/build-1"))
+
+	require.True(t, pattern.IsPreRelease("ios/v1.2.0-rc.1"))
+	require.False(t, pattern.IsPreRelease("ios/v1.2.0"))
+
+	v, err := pattern.Version("ios/v1.2.0")
+	require.NoError(t, err)
+	require.Equal(t, "1.2.0", v.String())
+}
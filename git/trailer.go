@@ -0,0 +1,35 @@
+package git
+
+import "strings"
+
+// Trailer is a `Key: value` line in the last paragraph of the commit message,
+// like Signed-off-by, Co-authored-by, Reviewed-by or Refs.
+type Trailer struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func parseTrailers(body string) []Trailer {
+	var trailers []Trailer
+	for _, footer := range parseFooters(body) {
+		trailers = append(trailers, Trailer{Key: footer.Token, Value: footer.Value})
+	}
+	return trailers
+}
+
+// TrailerValues returns the values of the trailers with the given key (case-insensitive).
+func (c Commit) TrailerValues(key string) []string {
+	var values []string
+	for _, trailer := range c.Trailers {
+		if strings.EqualFold(trailer.Key, key) {
+			values = append(values, trailer.Value)
+		}
+	}
+	return values
+}
+
+// BodyWithoutTrailers returns the body of the commit message without the trailers.
+func (c Commit) BodyWithoutTrailers() string {
+	text, _ := splitFooters(c.Body)
+	return text
+}
@@ -0,0 +1,46 @@
+package git
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommit_Trailers(t *testing.T) {
+	body := `Explain the change.
+
+Co-authored-by: Bob <bob@example.com>
+Signed-off-by: Alice <alice@example.com>
+signed-off-by: Carol <carol@example.com>
+Refs: #42`
+	commit := newCommit("hash", "Add login", body, time.Unix(0, 0), "Alice")
+
+	require.Equal(t, []Trailer{
+		{Key: "Co-authored-by", Value: "Bob <bob@example.com>"},
+		{Key: "Signed-off-by", Value: "Alice <alice@example.com>"},
+		{Key: "signed-off-by", Value: "Carol <carol@example.com>"},
+		{Key: "Refs", Value: "#42"},
+	}, commit.Trailers)
+	require.Equal(t, []string{"Alice <alice@example.com>", "Carol <carol@example.com>"}, commit.TrailerValues("Signed-off-by"))
+	require.Nil(t, commit.TrailerValues("Reviewed-by"))
+	require.Equal(t, "Explain the change.", commit.BodyWithoutTrailers())
+}
+
+func TestCommit_BodyWithoutTrailers(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "empty", body: "", want: ""},
+		{name: "no trailers", body: "First paragraph.\n\nSecond paragraph.", want: "First paragraph.\n\nSecond paragraph."},
+		{name: "only trailers", body: "Signed-off-by: Alice <alice@example.com>", want: ""},
+		{name: "last paragraph is text", body: "Refs: #1\n\nSome text.", want: "Refs: #1\n\nSome text."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, Commit{Body: tt.body}.BodyWithoutTrailers())
+		})
+	}
+}
@@ -0,0 +1,49 @@
+// Package gittest provides throwaway git repositories for the tests of the git commands and the release ranges.
+package gittest
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Repo is a throwaway git repository in a temporary directory of the test.
+type Repo struct {
+	t   *testing.T
+	Dir string
+}
+
+// New initializes an empty repository.
+func New(t *testing.T) Repo {
+	r := Repo{t: t, Dir: t.TempDir()}
+	r.Git("init", "-q")
+	return r
+}
+
+// Git runs a git command in the repository and returns its trimmed output, the test fails if the command fails.
+func (r Repo) Git(args ...string) string {
+	return r.GitWithEnv(nil, args...)
+}
+
+// GitWithEnv runs a git command with additional env vars (GIT_AUTHOR_DATE=...).
+func (r Repo) GitWithEnv(envs []string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.Dir
+	cmd.Env = append(append(os.Environ(),
+		"GIT_AUTHOR_NAME=Alice", "GIT_AUTHOR_EMAIL=alice@example.com",
+		"GIT_COMMITTER_NAME=Alice", "GIT_COMMITTER_EMAIL=alice@example.com",
+		"GIT_CONFIG_NOSYSTEM=1", "HOME="+r.Dir,
+	), envs...)
+	out, err := cmd.CombinedOutput()
+	require.NoError(r.t, err, string(out))
+	return strings.TrimSpace(string(out))
+}
+
+// Commit creates an empty commit with the given message and date (RFC 3339) and returns its hash.
+func (r Repo) Commit(message, date string) string {
+	r.GitWithEnv([]string{"GIT_AUTHOR_DATE=" + date, "GIT_COMMITTER_DATE=" + date}, "commit", "-q", "--allow-empty", "-m", message)
+	return r.Git("rev-parse", "HEAD")
+}
@@ -0,0 +1,127 @@
+package issues
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Pattern matches the issue references of an issue tracker and builds their URL.
+// The URL template can refer to the whole reference with {key},
+// and to the first capture group of the pattern (or to the whole reference if there is none) with {id}.
+type Pattern struct {
+	regexp      *regexp.Regexp
+	urlTemplate string
+}
+
+// Reference is an issue reference found in a commit message.
+type Reference struct {
+	Key string `json:"key"`
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// ParsePatterns parses the issue patterns, one per line in `<regex> <url template>` format:
+//
+//	#(\d+) https://github.com/owner/repo/issues/{id}
+//	JIRA-\d+ https://jira.example.com/browse/{key}
+func ParsePatterns(lines []string) ([]Pattern, error) {
+	var patterns []Pattern
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		idx := strings.LastIndexAny(line, " \t")
+		if idx == -1 {
+			return nil, fmt.Errorf("invalid issue pattern (%s), expected format: <regex> <url template>", line)
+		}
+
+		re, err := regexp.Compile(strings.TrimSpace(line[:idx]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid issue pattern (%s): %s", line, err)
+		}
+
+		patterns = append(patterns, Pattern{regexp: re, urlTemplate: line[idx+1:]})
+	}
+	return patterns, nil
+}
+
+type match struct {
+	start, end int
+	ref        Reference
+}
+
+func (p Pattern) matches(text string) []match {
+	var matches []match
+	for _, loc := range p.regexp.FindAllStringSubmatchIndex(text, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+
+		key := text[loc[0]:loc[1]]
+		id := key
+		if len(loc) > 3 && loc[2] != -1 {
+			id = text[loc[2]:loc[3]]
+		}
+		url := strings.NewReplacer("{key}", key, "{id}", id).Replace(p.urlTemplate)
+
+		matches = append(matches, match{start: loc[0], end: loc[1], ref: Reference{Key: key, ID: id, URL: url}})
+	}
+	return matches
+}
+
+// findMatches returns the non-overlapping matches of the patterns, ordered by their position.
+// If matches overlap, the one starting first wins, then the one of the pattern listed first.
+func findMatches(patterns []Pattern, text string) []match {
+	var all []match
+	for _, p := range patterns {
+		all = append(all, p.matches(text)...)
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].start < all[j].start
+	})
+
+	var matches []match
+	end := 0
+	for _, m := range all {
+		if m.start < end {
+			continue
+		}
+		matches = append(matches, m)
+		end = m.end
+	}
+	return matches
+}
+
+// Find returns the de-duplicated issue references of the texts, in the order of their first occurrence.
+func Find(patterns []Pattern, texts ...string) []Reference {
+	var refs []Reference
+	seen := map[string]bool{}
+	for _, text := range texts {
+		for _, m := range findMatches(patterns, text) {
+			if seen[m.ref.URL] {
+				continue
+			}
+			seen[m.ref.URL] = true
+			refs = append(refs, m.ref)
+		}
+	}
+	return refs
+}
+
+// Replace replaces the issue references of the text with link(reference),
+// the rest of the text is replaced with escape(text).
+func Replace(patterns []Pattern, text string, link func(Reference) string, escape func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range findMatches(patterns, text) {
+		b.WriteString(escape(text[last:m.start]))
+		b.WriteString(link(m.ref))
+		last = m.end
+	}
+	b.WriteString(escape(text[last:]))
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package issues
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePatterns(t *testing.T) {
+	patterns, err := ParsePatterns([]string{"", `#(\d+) https://github.com/owner/repo/issues/{id}`})
+	require.NoError(t, err)
+	require.Len(t, patterns, 1)
+
+	_, err = ParsePatterns([]string{`#(\d+)`})
+	require.Error(t, err)
+
+	_, err = ParsePatterns([]string{`#(\d+ https://example.com/{id}`})
+	require.Error(t, err)
+}
+
+func TestFind(t *testing.T) {
+	patterns, err := ParsePatterns([]string{
+		`#(\d+) https://github.com/owner/repo/issues/{id}`,
+		`(?i)\bTOOL-\d+ https://jira.example.com/browse/{key}`,
+	})
+	require.NoError(t, err)
+
+	refs := Find(patterns, "Tool-248 firebase (#7)", "Refs: #7, #8 and TOOL-1")
+	require.Equal(t, []Reference{
+		{Key: "Tool-248", ID: "Tool-248", URL: "https://jira.example.com/browse/Tool-248"},
+		{Key: "#7", ID: "7", URL: "https://github.com/owner/repo/issues/7"},
+		{Key: "#8", ID: "8", URL: "https://github.com/owner/repo/issues/8"},
+		{Key: "TOOL-1", ID: "TOOL-1", URL: "https://jira.example.com/browse/TOOL-1"},
+	}, refs)
+
+	require.Empty(t, Find(nil, "Tool-248 firebase (#7)"))
+}
+
+func TestReplace(t *testing.T) {
+	patterns, err := ParsePatterns([]string{
+		`#(\d+) https://github.com/owner/repo/issues/{id}`,
+		`#\d+ https://example.com/never-used/{key}`,
+	})
+	require.NoError(t, err)
+
+	got := Replace(patterns, "Fix *bold* (#7)",
+		func(ref Reference) string { return "<" + ref.URL + ">" },
+		func(s string) string { return "[" + s + "]" },
+	)
+	require.Equal(t, "[Fix *bold* (]<https://github.com/owner/repo/issues/7>[)]", got)
+}
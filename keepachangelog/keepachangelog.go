@@ -0,0 +1,135 @@
+package keepachangelog
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Unreleased is the title of the section collecting the changes since the last release.
+const Unreleased = "Unreleased"
+
+const header = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/).
+`
+
+// ErrVersionExists is returned by Insert if the version is already in the changelog.
+var ErrVersionExists = errors.New("version is already in the changelog")
+
+// release section headings: ## [1.0.0] - 2020-01-01, ## 1.0.0 or ## [Unreleased]
+var releaseHeadingRegexp = regexp.MustCompile(`^##\s+\[?([^\]\s]+)\]?`)
+
+type section struct {
+	version string
+	lines   []string
+}
+
+// Heading returns the Keep a Changelog heading of a release section, the version is written without the "v" prefix.
+// If version is empty, the heading of the Unreleased section is returned.
+func Heading(version string, date time.Time) string {
+	if version == "" || strings.EqualFold(version, Unreleased) {
+		return fmt.Sprintf("## [%s]", Unreleased)
+	}
+	return fmt.Sprintf("## [%s] - %s", normalizeVersion(version), date.Format("2006-01-02"))
+}
+
+// normalizeVersion removes the "v" prefix of a version (v1.0.1 is 1.0.1).
+func normalizeVersion(version string) string {
+	if len(version) > 1 && (version[0] == 'v' || version[0] == 'V') && version[1] >= '0' && version[1] <= '9' {
+		return version[1:]
+	}
+	return version
+}
+
+func sameVersion(a, b string) bool {
+	return strings.EqualFold(normalizeVersion(a), normalizeVersion(b))
+}
+
+// Insert adds the release section with the given body to the changelog following the Keep a Changelog layout
+// (https://keepachangelog.com).
+// The release section replaces the Unreleased section if there is one, otherwise it is inserted above the latest release.
+// If version is empty, the body is added as the Unreleased section.
+// Inserting a version which is already in the changelog (1.0.1 and v1.0.1 are the same version) returns ErrVersionExists.
+// Any other content of the changelog is kept as is.
+func Insert(changelog, version string, date time.Time, body string) (string, error) {
+	if version == "" {
+		version = Unreleased
+	}
+	release := strings.TrimRight(Heading(version, date)+"\n"+strings.Trim(body, "\n"), "\n") + "\n"
+
+	if strings.TrimSpace(changelog) == "" {
+		return header + "\n" + release, nil
+	}
+
+	preamble, sections := parse(changelog)
+	for _, s := range sections {
+		if sameVersion(s.version, version) && !strings.EqualFold(version, Unreleased) {
+			return "", fmt.Errorf("%w: %s", ErrVersionExists, version)
+		}
+	}
+
+	var lines []string
+	lines = append(lines, preamble...)
+	inserted := false
+	for _, s := range sections {
+		if !inserted && strings.EqualFold(s.version, Unreleased) {
+			lines = append(lines, releaseLines(release, s.lines)...)
+			inserted = true
+			continue
+		}
+		if !inserted {
+			lines = append(lines, releaseLines(release, nil)...)
+			inserted = true
+		}
+		lines = append(lines, s.lines...)
+	}
+	if !inserted {
+		if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+			lines = append(lines, "")
+		}
+		lines = append(lines, releaseLines(release, nil)...)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// releaseLines returns the lines of the release section, followed by the trailing blank lines of the replaced section.
+func releaseLines(release string, replaced []string) []string {
+	lines := strings.Split(strings.TrimSuffix(release, "\n"), "\n")
+
+	trailing := 1
+	if replaced != nil {
+		trailing = 0
+		for i := len(replaced) - 1; i >= 0 && strings.TrimSpace(replaced[i]) == ""; i-- {
+			trailing++
+		}
+	}
+	for i := 0; i < trailing; i++ {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// parse splits the changelog into the content preceding the first release section and the release sections.
+func parse(changelog string) ([]string, []section) {
+	var preamble []string
+	var sections []section
+	for _, line := range strings.Split(changelog, "\n") {
+		if match := releaseHeadingRegexp.FindStringSubmatch(line); match != nil {
+			sections = append(sections, section{version: match[1]})
+		}
+
+		if len(sections) == 0 {
+			preamble = append(preamble, line)
+		} else {
+			last := &sections[len(sections)-1]
+			last.lines = append(last.lines, line)
+		}
+	}
+	return preamble, sections
+}
@@ -0,0 +1,108 @@
+package keepachangelog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsert(t *testing.T) {
+	date := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	body := "### Features\n* [1111111] login screen\n"
+
+	t.Run("new changelog", func(t *testing.T) {
+		got, err := Insert("", "1.1.0", date, body)
+		require.NoError(t, err)
+		require.Equal(t, header+`
+## [1.1.0] - 2020-01-02
+### Features
+* [1111111] login screen
+`, got)
+	})
+
+	t.Run("inserted above the latest release", func(t *testing.T) {
+		got, err := Insert(`# Changelog
+
+Hand-written intro.
+
+## [1.0.0] - 2019-01-01
+### Added
+- Hand-written entry
+
+[1.0.0]: https://example.com/1.0.0
+`, "1.1.0", date, body)
+		require.NoError(t, err)
+		require.Equal(t, `# Changelog
+
+Hand-written intro.
+
+## [1.1.0] - 2020-01-02
+### Features
+* [1111111] login screen
+
+## [1.0.0] - 2019-01-01
+### Added
+- Hand-written entry
+
+[1.0.0]: https://example.com/1.0.0
+`, got)
+	})
+
+	t.Run("replaces the unreleased section", func(t *testing.T) {
+		got, err := Insert(`# Changelog
+
+## [Unreleased]
+- work in progress
+
+## 1.0.0
+- first release
+`, "1.1.0", date, body)
+		require.NoError(t, err)
+		require.Equal(t, `# Changelog
+
+## [1.1.0] - 2020-01-02
+### Features
+* [1111111] login screen
+
+## 1.0.0
+- first release
+`, got)
+	})
+
+	t.Run("unreleased changes", func(t *testing.T) {
+		got, err := Insert(`# Changelog
+## [Unreleased]
+- work in progress
+`, "", date, body)
+		require.NoError(t, err)
+		require.Equal(t, `# Changelog
+## [Unreleased]
+### Features
+* [1111111] login screen
+`, got)
+	})
+
+	t.Run("changelog without releases", func(t *testing.T) {
+		got, err := Insert("# Changelog\n", "1.1.0", date, body)
+		require.NoError(t, err)
+		require.Equal(t, "# Changelog\n\n## [1.1.0] - 2020-01-02\n### Features\n* [1111111] login screen\n", got)
+	})
+
+	t.Run("version already present", func(t *testing.T) {
+		_, err := Insert("# Changelog\n\n## [1.1.0] - 2020-01-01\n- entry\n", "1.1.0", date, body)
+		require.ErrorIs(t, err, ErrVersionExists)
+
+		_, err = Insert("# Changelog\n\n## [1.1.0] - 2020-01-01\n- entry\n", "v1.1.0", date, body)
+		require.ErrorIs(t, err, ErrVersionExists)
+
+		_, err = Insert("# Changelog\n\n## [v1.1.0] - 2020-01-01\n- entry\n", "1.1.0", date, body)
+		require.ErrorIs(t, err, ErrVersionExists)
+	})
+
+	t.Run("v prefix", func(t *testing.T) {
+		got, err := Insert("# Changelog\n\n## [1.0.0] - 2020-01-01\n- entry\n", "v1.1.0", date, body)
+		require.NoError(t, err)
+		require.Equal(t, "# Changelog\n\n## [1.1.0] - 2020-01-02\n### Features\n* [1111111] login screen\n\n## [1.0.0] - 2020-01-01\n- entry\n", got)
+	})
+}
@@ -1,123 +1,318 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"text/template"
 
 	"github.com/bitrise-io/go-steputils/stepconf"
 	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-steplib/steps-generate-changelog/exporter"
 	"github.com/bitrise-steplib/steps-generate-changelog/git"
-	"github.com/pkg/errors"
+	"github.com/bitrise-steplib/steps-generate-changelog/issues"
+	"github.com/bitrise-steplib/steps-generate-changelog/keepachangelog"
 )
 
-const changelogContentEnvKey = "BITRISE_CHANGELOG"
+const (
+	changelogContentEnvKey = "BITRISE_CHANGELOG"
+	changelogIssuesEnvKey  = "BITRISE_CHANGELOG_ISSUES"
+	pullRequestsEnvKey     = "BITRISE_CHANGELOG_PULL_REQUESTS"
+)
+
+const (
+	overwriteUpdateMode = "overwrite"
+	prependUpdateMode   = "prepend"
+)
 
 func failf(format string, args ...interface{}) {
 	log.Errorf(format, args...)
 	os.Exit(1)
 }
 
-func releaseCommits(dir string) ([]git.Commit, error) {
-	startCommit, err := git.FirstCommit(dir)
-	if err != nil {
-		return nil, errors.WithStack(err)
-	}
+// Config ...
+type Config struct {
+	ChangelogPath string `env:"changelog_pth,required"`
+	UpdateMode    string `env:"update_mode,opt[overwrite,prepend]"`
+	WorkDir       string `env:"working_dir,required"`
+	FromRef       string `env:"from_ref"`
+	ToRef         string `env:"to_ref"`
+	History       bool   `env:"generate_history,opt[yes,no]"`
+	MergeCommits  string `env:"merge_commits,opt[exclude,include,only,first-parent]"`
 
-	endCommit, err := git.LastCommit(dir)
-	if err != nil {
-		return nil, errors.WithStack(err)
-	}
+	TagPattern      string   `env:"tag_pattern"`
+	TagPrefix       string   `env:"tag_prefix"`
+	Paths           []string `env:"paths,multiline"`
+	SkipPreReleases bool     `env:"skip_prereleases,opt[yes,no]"`
 
-	taggedCommits, err := git.TaggedCommits(dir)
-	if err != nil {
-		return nil, errors.WithStack(err)
-	}
+	PreReleaseChannel string `env:"prerelease_channel"`
 
-	includeFirst := true
-	if len(taggedCommits) > 1 {
-		// collecting changelog between existing versions
-		startCommit = taggedCommits[len(taggedCommits)-2]
-		endCommit = taggedCommits[len(taggedCommits)-1]
-		includeFirst = false
-	}
+	OutputFormat          string `env:"output_format,opt[markdown,json,html,text]"`
+	ChangelogTemplate     string `env:"changelog_template"`
+	ChangelogTemplatePath string `env:"changelog_template_path"`
 
-	commits, err := git.Commits(dir)
-	if err != nil {
-		return nil, errors.WithStack(err)
-	}
+	SectionTitles    []string `env:"section_titles,multiline"`
+	HiddenTypes      []string `env:"hidden_types,multiline"`
+	IncludeBody      bool     `env:"include_body,opt[yes,no]"`
+	ListContributors bool     `env:"list_contributors,opt[yes,no]"`
+	UseMailmap       bool     `env:"use_mailmap,opt[yes,no]"`
+	IssuePatterns    []string `env:"issue_patterns,multiline"`
 
-	var releaseCommits []git.Commit
-	for _, commit := range commits {
-		if commit.Date.Before(startCommit.Date) {
-			continue
-		}
-		if !includeFirst && commit.Date.Equal(startCommit.Date) {
-			continue
-		}
-		if commit.Date.After(endCommit.Date) {
-			break
-		}
-		releaseCommits = append(releaseCommits, commit)
-	}
+	IncludeMessages []string `env:"include_messages,multiline"`
+	ExcludeMessages []string `env:"exclude_messages,multiline"`
+	IncludeAuthors  []string `env:"include_authors,multiline"`
+	ExcludeAuthors  []string `env:"exclude_authors,multiline"`
+	IncludePaths    []string `env:"include_paths,multiline"`
+	ExcludePaths    []string `env:"exclude_paths,multiline"`
 
-	return releaseCommits, nil
+	ReleaseNotesLocales     []string `env:"release_notes_locales,multiline"`
+	ReleaseNotesLimitPolicy string   `env:"release_notes_limit_policy,opt[truncate,fail]"`
+	WhatsNewDir             string   `env:"whatsnew_dir"`
+	FastlaneMetadataDir     string   `env:"fastlane_metadata_dir"`
+
+	TruncationFooter string `env:"truncation_footer"`
+
+	Exporters        []string `env:"exporters,multiline"`
+	GitLabDotenvPath string   `env:"gitlab_dotenv_pth"`
+	DotenvPath       string   `env:"dotenv_pth"`
+
+	LinkCommits        bool   `env:"link_commits,opt[yes,no]"`
+	RepositoryURL      string `env:"repository_url"`
+	RepositoryProvider string `env:"repository_provider,opt[auto,github,gitlab,bitbucket,azure]"`
 }
 
-// Config ...
-type Config struct {
-	ChangelogPath string `env:"changelog_pth,required"`
-	WorkDir       string `env:"working_dir,required"`
+// inputDefaults are the step.yml defaults of the inputs with value options.
+// They are used if the input is empty, like when the step runs outside of Bitrise.
+var inputDefaults = map[string]string{
+	"update_mode":                overwriteUpdateMode,
+	"generate_history":           "no",
+	"merge_commits":              string(git.ExcludeMerges),
+	"skip_prereleases":           "no",
+	"output_format":              markdownFormat,
+	"include_body":               "no",
+	"list_contributors":          "no",
+	"use_mailmap":                "no",
+	"release_notes_limit_policy": truncateLimitPolicy,
+	"link_commits":               "no",
+	"repository_provider":        "auto",
+}
+
+// inputEnvProvider returns the inputDefaults for the empty inputs.
+type inputEnvProvider struct {
+	getenv func(key string) string
+}
+
+func (p inputEnvProvider) Getenv(key string) string {
+	if value := p.getenv(key); value != "" {
+		return value
+	}
+	return inputDefaults[key]
 }
 
 type outputExporter interface {
 	EnvKey() string
 	Filepath() string
 	WriteFile(content string) error
-	ExportEnv(value string) error
-	MaxEnvBytes() (int, error)
+	ExportEnv(value string, truncate exporter.TruncateFunc) error
+	ExportOutput(key, value string) error
+	ExportOutputs(outputs ...exporter.Output) error
 }
 
-func exportChangelog(changelog string, e outputExporter) error {
+// exportChangelog writes the changelog file and exports the changelog with every backend of the exporter.
+// The changelog is truncated to the size limit of each backend separately.
+func exportChangelog(changelog, format string, e outputExporter, footer *template.Template) error {
 	if err := e.WriteFile(changelog); err != nil {
 		return fmt.Errorf("unable to write changelog to (%s), error: %s", e.Filepath(), err)
 	}
 
-	maxEnvBytes, err := e.MaxEnvBytes()
-	if err != nil {
-		return fmt.Errorf("unable to load envman configs, error: %s", err)
+	truncate := func(value string, maxBytes int, size func(string) int) (string, error) {
+		filepth := e.Filepath()
+		if format == jsonFormat {
+			// a cut JSON document is not valid JSON
+			return "", fmt.Errorf("the JSON changelog (%d bytes) exceeds the maximum allowed size of the output (%d bytes), use the exported changelog file (%s) instead", size(value), maxBytes, filepth)
+		}
+		log.Warnf("Changelog content exceeds the maximum allowed size of the output (%d bytes).", maxBytes)
+		log.Warnf("The changelog's content will be trimmed to fit the maximum allowed size.")
+		log.Warnf("It is possible to modify the limit by following this guide: https://devcenter.bitrise.io/tips-and-tricks/increasing-the-size-limit-of-env-vars")
+		if filepth != "" {
+			log.Warnf("or you can use the exported changelog file(%s) also.", filepth)
+		}
+		return truncateChangelog(value, maxBytes, size, footer, filepth)
+	}
+	if err := e.ExportEnv(changelog, truncate); err != nil {
+		return fmt.Errorf("unable to export the changelog (%s), error: %s", e.EnvKey(), err)
+	}
+
+	return nil
+}
+
+// repository returns the repository used for linking the commits,
+// the repository_url input or the origin remote if the input is not set.
+func repository(c Config) git.Repository {
+	provider := c.RepositoryProvider
+	if provider == "auto" {
+		provider = ""
 	}
 
-	if maxEnvBytes > 0 {
-		if len(changelog) > maxEnvBytes {
-			log.Warnf("Changelog content exceeds the maximum allowed size to set in an environment variable. (%dKB)", maxEnvBytes/1024)
-			log.Warnf("The changelog's content will be trimmed to fit the maximum allowed size.")
-			log.Warnf("It is possible to modify the limit by following this guide: https://devcenter.bitrise.io/tips-and-tricks/increasing-the-size-limit-of-env-vars")
-			log.Warnf("or you can use the exported changelog file(%s) also.", e.Filepath())
-			changelog = changelog[:maxEnvBytes-4] + "\n..."
+	if c.RepositoryURL != "" {
+		repo, err := git.ParseRepository(c.RepositoryURL, provider)
+		if err != nil {
+			failf("Failed to parse repository URL, error: %s", err)
 		}
+		return repo
 	}
 
-	if err := e.ExportEnv(changelog); err != nil {
-		return fmt.Errorf("unable to export environment variable with envman (%s), error: %s", e.EnvKey(), err)
+	remoteURL, err := git.RemoteURL(c.WorkDir, "origin")
+	if err != nil {
+		log.Warnf("Unable to get the URL of the origin remote, commits will not be linked: %s", err)
+		return git.Repository{}
 	}
 
-	return nil
+	repo, err := git.ParseRepository(remoteURL, provider)
+	if err != nil {
+		log.Warnf("Commits will not be linked: %s", err)
+		log.Warnf("Set the repository_url and repository_provider inputs for self-hosted repositories.")
+		return git.Repository{}
+	}
+	return repo
+}
+
+// changelogVersion returns the version of the release tag in the changelog file,
+// without the tag prefix and the "v" prefix (ios/v1.0.1 is 1.0.1).
+func changelogVersion(tag string, pattern git.TagPattern) string {
+	if v, err := pattern.Version(tag); err == nil {
+		return v.String()
+	}
+	return strings.TrimPrefix(tag, pattern.Prefix())
 }
 
 func main() {
 	var c Config
-	if err := stepconf.Parse(&c); err != nil {
+	if err := stepconf.NewEnvParser(inputEnvProvider{getenv: os.Getenv}).Parse(&c); err != nil {
 		failf("Failed to parse configs, error: %s", err)
 	}
 	stepconf.Print(c)
 
-	commits, err := releaseCommits(c.WorkDir)
+	if c.UpdateMode == prependUpdateMode && c.OutputFormat != markdownFormat {
+		failf("The %s update mode is only supported with the %s output format", prependUpdateMode, markdownFormat)
+	}
+	if c.UpdateMode == prependUpdateMode && c.History {
+		failf("The %s update mode can not be used when generating the history", prependUpdateMode)
+	}
+
+	if c.PreReleaseChannel != "" && !preReleaseChannelRegexp.MatchString(c.PreReleaseChannel) {
+		failf("Invalid pre-release channel (%s), only alphanumerics and hyphens are allowed", c.PreReleaseChannel)
+	}
+
+	locales := nonEmptyLines(c.ReleaseNotesLocales)
+	if (c.WhatsNewDir != "" || c.FastlaneMetadataDir != "") && len(locales) == 0 {
+		failf("At least one release notes locale is required for writing the store release notes files")
+	}
+
+	backends := nonEmptyLines(c.Exporters)
+	if len(backends) == 0 {
+		backends = []string{exporter.EnvmanBackend, exporter.FileBackend}
+	}
+	multiExporter, err := exporter.NewMulti(changelogContentEnvKey, c.ChangelogPath, backends, exporter.BackendConfig{
+		GitLabDotenvPath: c.GitLabDotenvPath,
+		DotenvPath:       c.DotenvPath,
+	})
+	if err != nil {
+		failf("Failed to create exporter: %s", err)
+	}
+
+	truncationFooter, err := parseTruncationFooter(c.TruncationFooter)
+	if err != nil {
+		failf("Failed to parse truncation footer, error: %s", err)
+	}
+
+	tmplStr, err := changelogTemplate(c.ChangelogTemplate, c.ChangelogTemplatePath)
 	if err != nil {
-		failf("Failed to get release commits, error: %v", err)
+		failf("Failed to load changelog template, error: %s", err)
 	}
 
-	content, err := changelogContent(commits)
+	sectionCfg, err := newSectionConfig(c.SectionTitles, c.HiddenTypes)
+	if err != nil {
+		failf("Failed to parse section configs, error: %s", err)
+	}
+
+	issuePatterns, err := issues.ParsePatterns(c.IssuePatterns)
+	if err != nil {
+		failf("Failed to parse issue patterns, error: %s", err)
+	}
+
+	changelogCfg := changelogConfig{
+		Format:           c.OutputFormat,
+		Template:         tmplStr,
+		Sections:         sectionCfg,
+		IssuePatterns:    issuePatterns,
+		IncludeBody:      c.IncludeBody,
+		ListContributors: c.ListContributors,
+	}
+	if c.LinkCommits {
+		changelogCfg.Repository = repository(c)
+	}
+
+	tagPattern, err := git.ParseTagPattern(c.TagPattern)
+	if err != nil {
+		failf("Failed to parse tag pattern, error: %s", err)
+	}
+	tagPattern = tagPattern.WithPrefix(c.TagPrefix)
+
+	releaseCfg := releaseConfig{
+		FromRef:         c.FromRef,
+		ToRef:           c.ToRef,
+		TagPattern:      tagPattern,
+		SkipPreReleases: c.SkipPreReleases,
+		MergeCommits:    git.MergeMode(c.MergeCommits),
+		Paths:           nonEmptyLines(c.Paths),
+	}
+
+	filter, err := newCommitFilter(c.IncludeMessages, c.ExcludeMessages, c.IncludeAuthors, c.ExcludeAuthors, c.IncludePaths, c.ExcludePaths)
+	if err != nil {
+		failf("Failed to parse commit filters, error: %s", err)
+	}
+
+	taggedCommits, err := git.TaggedCommits(c.WorkDir, tagPattern)
+	if err != nil {
+		failf("Failed to get tagged commits, error: %v", err)
+	}
+
+	var releases []release
+	if c.History {
+		releases, err = releaseHistory(c.WorkDir, releaseCfg, taggedCommits)
+		if err != nil {
+			failf("Failed to get release history, error: %v", err)
+		}
+	} else {
+		r, err := releaseCommits(c.WorkDir, releaseCfg, taggedCommits)
+		if err != nil {
+			failf("Failed to get release commits, error: %v", err)
+		}
+		releases = []release{r}
+	}
+
+	releases, filtered, err := filterReleases(c.WorkDir, releases, filter)
+	if err != nil {
+		failf("Failed to filter commits, error: %v", err)
+	}
+	log.Printf("Filtered out %d commits", filtered)
+
+	if c.UseMailmap {
+		if err := applyMailmap(c.WorkDir, releases); err != nil {
+			failf("Failed to apply mailmap, error: %v", err)
+		}
+	}
+
+	var content string
+	r := releases[0]
+	if c.History {
+		content, err = historyContent(releases, changelogCfg)
+	} else {
+		content, err = changelogContent(r, changelogCfg)
+	}
 	if err != nil {
 		failf("Failed to get changelog content, error: %s", err)
 	}
@@ -125,9 +320,99 @@ func main() {
 	log.Infof("\nChangelog:")
 	log.Printf(content)
 
-	if err := exportChangelog(content, exporter.New(changelogContentEnvKey, c.ChangelogPath)); err != nil {
-		failf("Failed to export changelog: %s", err)
+	if c.UpdateMode == prependUpdateMode {
+		version := changelogVersion(r.Tag, tagPattern)
+		multiExporter = multiExporter.WithMerge(func(existing, content string) (string, error) {
+			merged, err := keepachangelog.Insert(existing, version, r.EndCommit.Date, content)
+			if errors.Is(err, keepachangelog.ErrVersionExists) {
+				// builds between two releases generate the changelog of the last release again
+				log.Warnf("The changelog file (%s) is not updated: %s", c.ChangelogPath, err)
+				return existing, nil
+			}
+			return merged, err
+		})
+	}
+	var e outputExporter = multiExporter
+
+	var issueKeys []string
+	for _, ref := range releaseIssues(issuePatterns, releases...) {
+		issueKeys = append(issueKeys, ref.Key)
+	}
+	if err := e.ExportOutput(changelogIssuesEnvKey, strings.Join(issueKeys, "\n")); err != nil {
+		failf("Failed to export referenced issues: %s", err)
+	}
+	log.Donef("The referenced issues are available in the " + changelogIssuesEnvKey + " environment variable")
+
+	var pullRequestNumbers []string
+	for _, pr := range releasePullRequests(releases...) {
+		pullRequestNumbers = append(pullRequestNumbers, strconv.Itoa(pr.Number))
+	}
+	if err := e.ExportOutput(pullRequestsEnvKey, strings.Join(pullRequestNumbers, "\n")); err != nil {
+		failf("Failed to export pull requests: %s", err)
+	}
+	log.Donef("The pull request numbers are available in the " + pullRequestsEnvKey + " environment variable")
+
+	outputs := releaseOutputs(r)
+	if err := e.ExportOutputs(outputs...); err != nil {
+		failf("Failed to export release metadata: %s", err)
+	}
+	log.Donef("The release metadata is available in the following environment variables:")
+	for _, output := range outputs {
+		log.Printf("- %s: %s", output.Key, output.Value)
+	}
+
+	notesSections := releaseNotesSections(r, sectionCfg)
+	notesOutputs := releaseNotesOutputs(notesSections)
+	if err := e.ExportOutputs(notesOutputs...); err != nil {
+		failf("Failed to export release notes: %s", err)
+	}
+	log.Donef("The store release notes are available in the " + appStoreReleaseNotesEnvKey + " and " + googlePlayReleaseNotesEnvKey + " environment variables")
+
+	if c.WhatsNewDir != "" {
+		notes, err := limitedReleaseNotes(notesSections, exporter.WhatsNewMaxChars, c.ReleaseNotesLimitPolicy)
+		if err != nil {
+			failf("Failed to render Google Play release notes: %s", err)
+		}
+		w := exporter.NewWhatsNew(c.WhatsNewDir, locales)
+		if err := w.WriteFiles(notes); err != nil {
+			failf("Failed to write the whatsnew directory (%s): %s", w.Dir(), err)
+		}
+		log.Donef("The Google Play release notes are written to the %s directory", w.Dir())
+	}
+
+	if c.FastlaneMetadataDir != "" {
+		notes, err := limitedReleaseNotes(notesSections, exporter.FastlaneMaxChars, c.ReleaseNotesLimitPolicy)
+		if err != nil {
+			failf("Failed to render App Store release notes: %s", err)
+		}
+		f := exporter.NewFastlaneMetadata(c.FastlaneMetadataDir, locales)
+		if err := f.WriteFiles(notes); err != nil {
+			failf("Failed to write the fastlane metadata directory (%s): %s", f.Dir(), err)
+		}
+		log.Donef("The App Store release notes are written to the %s fastlane metadata directory", f.Dir())
 	}
 
+	unreleased, err := unreleasedRelease(c.WorkDir, releaseCfg, taggedCommits)
+	if err != nil {
+		failf("Failed to get unreleased commits, error: %v", err)
+	}
+	unreleasedReleases, _, err := filterReleases(c.WorkDir, []release{unreleased}, filter)
+	if err != nil {
+		failf("Failed to filter unreleased commits, error: %v", err)
+	}
+
+	versionOutputs := nextVersionOutputs(taggedCommits, tagPattern, unreleasedReleases[0].Commits, c.PreReleaseChannel)
+	if err := e.ExportOutputs(versionOutputs...); err != nil {
+		failf("Failed to export the next version: %s", err)
+	}
+	log.Donef("The recommended next version is available in the following environment variables:")
+	for _, output := range versionOutputs {
+		log.Printf("- %s: %s", output.Key, output.Value)
+	}
+
+	// the changelog is exported last: it gets what is left of the size limit of the backends after the other outputs
+	if err := exportChangelog(content, c.OutputFormat, e, truncationFooter); err != nil {
+		failf("Failed to export changelog: %s", err)
+	}
 	log.Donef("\nThe changelog content is available in the " + changelogContentEnvKey + " environment variable")
 }
@@ -8,7 +8,9 @@ import (
 	"testing"
 
 	"github.com/bitrise-io/envman/envman"
+	"github.com/bitrise-io/go-steputils/stepconf"
 	"github.com/bitrise-steplib/steps-generate-changelog/exporter"
+	"github.com/bitrise-steplib/steps-generate-changelog/git"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -36,30 +38,39 @@ func (e mockExporter) WriteFile(content string) error { //nolint
 }
 
 // ExportEnv ...
-func (e mockExporter) ExportEnv(value string) error { //nolint
-	args := e.Called(value)
+func (e mockExporter) ExportEnv(value string, truncate exporter.TruncateFunc) error { //nolint
+	args := e.Called(value, truncate)
 	return args.Error(0)
 }
 
-// MaxEnvBytes ...
-func (e mockExporter) MaxEnvBytes() (int, error) { //nolint
-	args := e.Called()
-	return args.Int(0), args.Error(1)
+// ExportOutput ...
+func (e mockExporter) ExportOutput(key, value string) error { //nolint
+	args := e.Called(key, value)
+	return args.Error(0)
+}
+
+// ExportOutputs ...
+func (e mockExporter) ExportOutputs(outputs ...exporter.Output) error { //nolint
+	args := e.Called(outputs)
+	return args.Error(0)
 }
 
 func Test_exportChangelog(t *testing.T) {
 	envmanConfigs, err := envman.GetConfigs()
 	require.NoError(t, err)
+	footer, err := parseTruncationFooter("")
+	require.NoError(t, err)
+	maxEnvBytes := envmanConfigs.EnvBytesLimitInKB * 1024
+	size := func(s string) int { return len(s) }
 
 	t.Run("ok - under limit", func(t *testing.T) {
 		mockExporter := mockExporter{}
 		mockContent := "content"
 
 		mockExporter.On("WriteFile", mockContent).Return(nil).Once()
-		mockExporter.On("MaxEnvBytes").Return(0, nil).Once()
-		mockExporter.On("ExportEnv", mockContent).Return(nil).Once()
+		mockExporter.On("ExportEnv", mockContent, mock.Anything).Return(nil).Once()
 
-		require.NoError(t, exportChangelog(mockContent, mockExporter)) //nolint
+		require.NoError(t, exportChangelog(mockContent, markdownFormat, mockExporter, footer)) //nolint
 
 		mockExporter.AssertExpectations(t)
 	})
@@ -68,39 +79,46 @@ func Test_exportChangelog(t *testing.T) {
 		mockContent := strings.Repeat("a", (envmanConfigs.EnvBytesLimitInKB+1)*1024)
 
 		mockExporter.On("WriteFile", mockContent).Return(nil).Once()
-		mockExporter.On("Filepath").Return("").Once()
-		mockExporter.On("MaxEnvBytes").Return(envmanConfigs.EnvBytesLimitInKB*1024, nil).Once()
-		mockExporter.On("ExportEnv", mock.MatchedBy( // check if input argument "content" was stripped
-			func(content string) bool {
-				return len(content) == envmanConfigs.EnvBytesLimitInKB*1024 &&
-					content[len(content)-4:] == "\n..."
-			})).Return(nil).Once()
+		mockExporter.On("Filepath").Return("/bitrise/deploy/CHANGELOG.md").Once()
+		mockExporter.On("ExportEnv", mockContent, mock.Anything).Run(func(args mock.Arguments) {
+			// the backend with the limit exports the truncated changelog
+			truncated, err := args.Get(1).(exporter.TruncateFunc)(mockContent, maxEnvBytes, size)
+			require.NoError(t, err)
+			require.Len(t, truncated, maxEnvBytes)
+			require.True(t, strings.HasSuffix(truncated, "\n...and 0 more commits, see CHANGELOG.md"))
+		}).Return(nil).Once()
 
-		require.NoError(t, exportChangelog(mockContent, mockExporter)) //nolint
+		require.NoError(t, exportChangelog(mockContent, markdownFormat, mockExporter, footer)) //nolint
 
 		mockExporter.AssertExpectations(t)
 	})
 
-	t.Run("error - unable to write file", func(t *testing.T) {
+	t.Run("error - JSON above limit", func(t *testing.T) {
 		mockExporter := mockExporter{}
-		mockContent := strings.Repeat("a", (envmanConfigs.EnvBytesLimitInKB+1)*1024)
+		mockContent := "{\"commits\": [" + strings.Repeat(" ", (envmanConfigs.EnvBytesLimitInKB+1)*1024) + "]}"
 
-		mockExporter.On("Filepath").Return("").Once()
-		mockExporter.On("WriteFile", mockContent).Return(errors.New("error")).Once()
+		mockExporter.On("WriteFile", mockContent).Return(nil).Once()
+		mockExporter.On("Filepath").Return("/bitrise/deploy/CHANGELOG.json").Once()
+		mockExporter.On("EnvKey").Return("BITRISE_CHANGELOG").Once()
+		mockExporter.On("ExportEnv", mockContent, mock.Anything).Run(func(args mock.Arguments) {
+			// a cut JSON document is not valid, the changelog is not truncated
+			_, err := args.Get(1).(exporter.TruncateFunc)(mockContent, maxEnvBytes, size)
+			require.Error(t, err)
+		}).Return(errors.New("envman: the JSON changelog exceeds the maximum allowed size")).Once()
 
-		require.Error(t, exportChangelog(mockContent, mockExporter)) //nolint
+		require.Error(t, exportChangelog(mockContent, jsonFormat, mockExporter, footer)) //nolint
 
 		mockExporter.AssertExpectations(t)
 	})
 
-	t.Run("error - unable to get envman config", func(t *testing.T) {
+	t.Run("error - unable to write file", func(t *testing.T) {
 		mockExporter := mockExporter{}
-		mockContent := "content"
+		mockContent := strings.Repeat("a", (envmanConfigs.EnvBytesLimitInKB+1)*1024)
 
-		mockExporter.On("WriteFile", mockContent).Return(nil).Once()
-		mockExporter.On("MaxEnvBytes").Return(0, errors.New("")).Once()
+		mockExporter.On("Filepath").Return("").Once()
+		mockExporter.On("WriteFile", mockContent).Return(errors.New("error")).Once()
 
-		require.Error(t, exportChangelog(mockContent, mockExporter)) //nolint
+		require.Error(t, exportChangelog(mockContent, markdownFormat, mockExporter, footer)) //nolint
 
 		mockExporter.AssertExpectations(t)
 	})
@@ -110,11 +128,11 @@ func Test_exportChangelog(t *testing.T) {
 		mockContent := "content"
 
 		mockExporter.On("WriteFile", mockContent).Return(nil).Once()
-		mockExporter.On("EnvKey").Return("").Once()
-		mockExporter.On("MaxEnvBytes").Return(0, nil).Once()
-		mockExporter.On("ExportEnv", mockContent).Return(errors.New("")).Once()
+		mockExporter.On("EnvKey").Return("BITRISE_CHANGELOG").Once()
+		mockExporter.On("ExportEnv", mockContent, mock.Anything).Return(errors.New("gitlab-dotenv: permission denied")).Once()
 
-		require.Error(t, exportChangelog(mockContent, mockExporter)) //nolint
+		err := exportChangelog(mockContent, markdownFormat, mockExporter, footer)
+		require.EqualError(t, err, "unable to export the changelog (BITRISE_CHANGELOG), error: gitlab-dotenv: permission denied")
 
 		mockExporter.AssertExpectations(t)
 	})
@@ -129,12 +147,46 @@ func TestContentEscaping(t *testing.T) {
 `
 	contentEnvKey := "TEST_CHANGELOG_CONTENT"
 	contentPath := filepath.Join(t.TempDir(), "changelog.md")
-	exporter := exporter.New(contentEnvKey, contentPath)
+	exporter, err := exporter.NewMulti(contentEnvKey, contentPath, []string{exporter.EnvmanBackend, exporter.FileBackend}, exporter.BackendConfig{})
+	require.NoError(t, err)
 	
-	err := exportChangelog(content, exporter)
+	footer, err := parseTruncationFooter("")
+	require.NoError(t, err)
+
+	err = exportChangelog(content, markdownFormat, exporter, footer)
 	require.NoError(t, err)
 
 	b, err := os.ReadFile(contentPath)
 	require.NoError(t, err)
 	require.Equal(t, content, string(b))
 }
+
+func Test_changelogVersion(t *testing.T) {
+	pattern, err := git.ParseTagPattern("")
+	require.NoError(t, err)
+
+	require.Equal(t, "1.0.1", changelogVersion("v1.0.1", pattern))
+	require.Equal(t, "1.0.1", changelogVersion("ios/v1.0.1", pattern.WithPrefix("ios/")))
+	require.Equal(t, "build-42", changelogVersion("ios/build-42", pattern.WithPrefix("ios/")))
+	require.Equal(t, "", changelogVersion("", pattern))
+}
+
+func TestConfig_defaults(t *testing.T) {
+	envs := map[string]string{
+		"changelog_pth": "CHANGELOG.md",
+		"working_dir":   ".",
+		"exporters":     "stdout",
+	}
+	var c Config
+	require.NoError(t, stepconf.NewEnvParser(inputEnvProvider{getenv: func(key string) string { return envs[key] }}).Parse(&c))
+	require.Equal(t, overwriteUpdateMode, c.UpdateMode)
+	require.Equal(t, markdownFormat, c.OutputFormat)
+	require.Equal(t, string(git.ExcludeMerges), c.MergeCommits)
+	require.Equal(t, truncateLimitPolicy, c.ReleaseNotesLimitPolicy)
+	require.Equal(t, "auto", c.RepositoryProvider)
+	require.False(t, c.History)
+
+	envs["output_format"] = "html"
+	require.NoError(t, stepconf.NewEnvParser(inputEnvProvider{getenv: func(key string) string { return envs[key] }}).Parse(&c))
+	require.Equal(t, htmlFormat, c.OutputFormat)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/exporter"
+)
+
+const (
+	tagEnvKey             = "BITRISE_CHANGELOG_TAG"
+	previousTagEnvKey     = "BITRISE_CHANGELOG_PREVIOUS_TAG"
+	commitCountEnvKey     = "BITRISE_CHANGELOG_COMMIT_COUNT"
+	firstCommitEnvKey     = "BITRISE_CHANGELOG_FIRST_COMMIT"
+	lastCommitEnvKey      = "BITRISE_CHANGELOG_LAST_COMMIT"
+	releaseDateEnvKey     = "BITRISE_CHANGELOG_RELEASE_DATE"
+	breakingChangesEnvKey = "BITRISE_CHANGELOG_BREAKING_CHANGES"
+)
+
+// releaseOutputs returns the metadata of the release as step outputs:
+// the tags, the number of commits, the oldest and the newest commit of the release, the release date
+// and whether the release has breaking changes.
+func releaseOutputs(r release) []exporter.Output {
+	commits := r.Commits
+	firstCommit, lastCommit := "", ""
+	if len(commits) > 0 {
+		// the commits are ordered like git log, newest first
+		firstCommit, lastCommit = commits[len(commits)-1].Hash, commits[0].Hash
+	}
+
+	releaseDate := ""
+	if !r.EndCommit.Date.IsZero() {
+		releaseDate = r.EndCommit.Date.UTC().Format(time.RFC3339)
+	}
+
+	breaking := false
+	for _, commit := range commits {
+		breaking = breaking || commit.Breaking
+	}
+
+	return []exporter.Output{
+		{Key: tagEnvKey, Value: r.Tag},
+		{Key: previousTagEnvKey, Value: r.PreviousTag},
+		{Key: commitCountEnvKey, Value: strconv.Itoa(len(commits))},
+		{Key: firstCommitEnvKey, Value: firstCommit},
+		{Key: lastCommitEnvKey, Value: lastCommit},
+		{Key: releaseDateEnvKey, Value: releaseDate},
+		{Key: breakingChangesEnvKey, Value: strconv.FormatBool(breaking)},
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/exporter"
+	"github.com/bitrise-steplib/steps-generate-changelog/git"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_releaseOutputs(t *testing.T) {
+	r := release{
+		// git log order, the commits of a rebase share the same date
+		Commits: []git.Commit{
+			{Hash: "3333", Date: time.Unix(1, 0)},
+			{Hash: "2222", Date: time.Unix(1, 0), ConventionalCommit: git.ConventionalCommit{Type: "feat", Breaking: true}},
+			{Hash: "1111", Date: time.Unix(1, 0)},
+		},
+		Tag:         "1.1.0",
+		PreviousTag: "1.0.0",
+		EndCommit:   git.Commit{Hash: "3333", Date: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}
+
+	require.Equal(t, []exporter.Output{
+		{Key: "BITRISE_CHANGELOG_TAG", Value: "1.1.0"},
+		{Key: "BITRISE_CHANGELOG_PREVIOUS_TAG", Value: "1.0.0"},
+		{Key: "BITRISE_CHANGELOG_COMMIT_COUNT", Value: "3"},
+		{Key: "BITRISE_CHANGELOG_FIRST_COMMIT", Value: "1111"},
+		{Key: "BITRISE_CHANGELOG_LAST_COMMIT", Value: "3333"},
+		{Key: "BITRISE_CHANGELOG_RELEASE_DATE", Value: "2020-01-02T03:04:05Z"},
+		{Key: "BITRISE_CHANGELOG_BREAKING_CHANGES", Value: "true"},
+	}, releaseOutputs(r))
+
+	require.Equal(t, []exporter.Output{
+		{Key: "BITRISE_CHANGELOG_TAG", Value: ""},
+		{Key: "BITRISE_CHANGELOG_PREVIOUS_TAG", Value: ""},
+		{Key: "BITRISE_CHANGELOG_COMMIT_COUNT", Value: "0"},
+		{Key: "BITRISE_CHANGELOG_FIRST_COMMIT", Value: ""},
+		{Key: "BITRISE_CHANGELOG_LAST_COMMIT", Value: ""},
+		{Key: "BITRISE_CHANGELOG_RELEASE_DATE", Value: ""},
+		{Key: "BITRISE_CHANGELOG_BREAKING_CHANGES", Value: "false"},
+	}, releaseOutputs(release{}))
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/exporter"
+	"github.com/bitrise-steplib/steps-generate-changelog/git"
+	"github.com/bitrise-steplib/steps-generate-changelog/semver"
+	"github.com/pkg/errors"
+)
+
+const (
+	nextVersionEnvKey = "BITRISE_CHANGELOG_NEXT_VERSION"
+	nextTagEnvKey     = "BITRISE_CHANGELOG_NEXT_TAG"
+	versionBumpEnvKey = "BITRISE_CHANGELOG_VERSION_BUMP"
+)
+
+// preReleaseChannelRegexp matches a semantic version pre-release identifier.
+var preReleaseChannelRegexp = regexp.MustCompile(`^[0-9A-Za-z-]+$`)
+
+// versionBump returns the kind of change of the commits by Conventional Commits:
+// major for breaking changes, minor for features and patch for fixes and performance improvements.
+// Other commits (docs, chore, non-conventional commits) do not bump the version.
+func versionBump(commits []git.Commit) semver.Bump {
+	bump := semver.NoBump
+	for _, commit := range commits {
+		switch {
+		case commit.Breaking:
+			return semver.MajorBump
+		case commit.Type == "feat":
+			bump = semver.MinorBump
+		case (commit.Type == "fix" || commit.Type == "perf") && bump == semver.NoBump:
+			bump = semver.PatchBump
+		}
+	}
+	return bump
+}
+
+// nextVersion recommends the version of the release following the tagged commits,
+// which contains the given unreleased commits.
+// The version is bumped from the latest final release; a pending pre-release (1.2.0-rc.1) is released
+// instead of a smaller bump. If channel is set (rc, beta), the next pre-release of the channel is returned.
+// The returned tag has the tag prefix and the "v" prefix of the latest tag.
+// No version is recommended if none of the unreleased commits bump the version.
+func nextVersion(taggedCommits []git.Commit, pattern git.TagPattern, commits []git.Commit, channel string) (semver.Version, string, bool) {
+	bump := versionBump(commits)
+	if bump == semver.NoBump {
+		return semver.Version{}, "", false
+	}
+
+	var versions []semver.Version
+	var latestFinal, latestPreRelease semver.Version
+	for _, commit := range taggedCommits {
+		v, err := pattern.Version(commit.Tag)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, v)
+
+		if v.IsPreRelease() {
+			if v.Compare(latestPreRelease) > 0 {
+				latestPreRelease = v
+			}
+		} else if v.Compare(latestFinal) > 0 {
+			latestFinal = v
+		}
+	}
+
+	next := latestFinal.Bump(bump)
+	if pending := latestPreRelease.Bump(semver.NoBump); pending.Compare(latestFinal) > 0 && pending.Compare(next) >= 0 {
+		next = pending
+	}
+	if channel != "" {
+		next = next.NextPreRelease(channel, versions)
+	}
+
+	tag := pattern.Prefix()
+	if latest, _, ok := latestVersionTag(taggedCommits, pattern); ok && strings.HasPrefix(strings.ToLower(strings.TrimPrefix(latest.Tag, tag)), "v") {
+		tag += "v"
+	}
+	return next, tag + next.String(), true
+}
+
+// latestVersionTag returns the tagged commit with the highest semantic version.
+func latestVersionTag(taggedCommits []git.Commit, pattern git.TagPattern) (git.Commit, semver.Version, bool) {
+	var latest git.Commit
+	var latestVersion semver.Version
+	found := false
+	for _, commit := range taggedCommits {
+		v, err := pattern.Version(commit.Tag)
+		if err != nil {
+			continue
+		}
+		if !found || v.Compare(latestVersion) > 0 {
+			latest, latestVersion, found = commit, v, true
+		}
+	}
+	return latest, latestVersion, found
+}
+
+// unreleasedRelease returns the commits since the latest semantic version tag, up to the to_ref (HEAD by default).
+func unreleasedRelease(dir string, cfg releaseConfig, taggedCommits []git.Commit) (release, error) {
+	toRef := cfg.ToRef
+	if toRef == "" {
+		toRef = "HEAD"
+	}
+	endCommit, err := git.RevisionCommit(dir, toRef)
+	if err != nil {
+		return release{}, errors.WithStack(err)
+	}
+
+	startCommit, _, hasStart := latestVersionTag(taggedCommits, cfg.TagPattern)
+	return rangeRelease(dir, cfg.logOptions(), nil, startCommit, endCommit, !hasStart)
+}
+
+// nextVersionOutputs returns the recommended next version, tag and the kind of the version bump as step outputs.
+// The outputs are empty if there are no unreleased commits.
+func nextVersionOutputs(taggedCommits []git.Commit, pattern git.TagPattern, commits []git.Commit, channel string) []exporter.Output {
+	version, tag, ok := nextVersion(taggedCommits, pattern, commits, channel)
+	if !ok {
+		return []exporter.Output{
+			{Key: nextVersionEnvKey},
+			{Key: nextTagEnvKey},
+			{Key: versionBumpEnvKey},
+		}
+	}
+	return []exporter.Output{
+		{Key: nextVersionEnvKey, Value: version.String()},
+		{Key: nextTagEnvKey, Value: tag},
+		{Key: versionBumpEnvKey, Value: versionBump(commits).String()},
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/git"
+	"github.com/bitrise-steplib/steps-generate-changelog/semver"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_versionBump(t *testing.T) {
+	fix := git.Commit{ConventionalCommit: git.ConventionalCommit{Type: "fix"}}
+	feat := git.Commit{ConventionalCommit: git.ConventionalCommit{Type: "feat"}}
+	breaking := git.Commit{ConventionalCommit: git.ConventionalCommit{Type: "fix", Breaking: true}}
+	perf := git.Commit{ConventionalCommit: git.ConventionalCommit{Type: "perf"}}
+	chore := git.Commit{ConventionalCommit: git.ConventionalCommit{Type: "chore"}}
+
+	require.Equal(t, semver.NoBump, versionBump(nil))
+	require.Equal(t, semver.PatchBump, versionBump([]git.Commit{fix, {Message: "Update readme"}}))
+	require.Equal(t, semver.PatchBump, versionBump([]git.Commit{perf}))
+	require.Equal(t, semver.NoBump, versionBump([]git.Commit{chore, {Message: "Update readme"}}))
+	require.Equal(t, semver.MinorBump, versionBump([]git.Commit{fix, feat, fix}))
+	require.Equal(t, semver.MajorBump, versionBump([]git.Commit{feat, breaking}))
+}
+
+func Test_nextVersion(t *testing.T) {
+	tagged := func(tags ...string) []git.Commit {
+		var commits []git.Commit
+		for _, tag := range tags {
+			commits = append(commits, git.Commit{Tag: tag})
+		}
+		return commits
+	}
+	fix := []git.Commit{{ConventionalCommit: git.ConventionalCommit{Type: "fix"}}}
+	feat := []git.Commit{{ConventionalCommit: git.ConventionalCommit{Type: "feat"}}}
+	breaking := []git.Commit{{ConventionalCommit: git.ConventionalCommit{Type: "feat", Breaking: true}}}
+
+	tests := []struct {
+		name     string
+		tags     []string
+		prefix   string
+		commits  []git.Commit
+		channel  string
+		wantTag  string
+		wantNone bool
+	}{
+		{name: "no commits", tags: []string{"1.0.0"}, wantNone: true},
+		{name: "no bump", tags: []string{"1.0.0"}, commits: []git.Commit{{ConventionalCommit: git.ConventionalCommit{Type: "docs"}}}, wantNone: true},
+		{name: "no tags", commits: feat, wantTag: "0.1.0"},
+		{name: "patch", tags: []string{"v1.0.0", "v1.1.0", "build-1"}, commits: fix, wantTag: "v1.1.1"},
+		{name: "minor", tags: []string{"1.1.0"}, commits: feat, wantTag: "1.2.0"},
+		{name: "major", tags: []string{"1.1.0"}, commits: breaking, wantTag: "2.0.0"},
+		{name: "first pre-release", tags: []string{"1.1.0"}, commits: feat, channel: "rc", wantTag: "1.2.0-rc.1"},
+		{name: "next pre-release", tags: []string{"1.1.0", "1.2.0-rc.1"}, commits: fix, channel: "rc", wantTag: "1.2.0-rc.2"},
+		{name: "other channel", tags: []string{"1.1.0", "1.2.0-rc.1"}, commits: fix, channel: "beta", wantTag: "1.2.0-beta.1"},
+		{name: "pending pre-release", tags: []string{"1.1.0", "1.2.0-rc.2"}, commits: fix, wantTag: "1.2.0"},
+		{name: "bump over pre-release", tags: []string{"1.1.0", "1.2.0-rc.2"}, commits: breaking, channel: "rc", wantTag: "2.0.0-rc.1"},
+		{name: "prefix", tags: []string{"ios/v1.0.0"}, prefix: "ios/", commits: fix, wantTag: "ios/v1.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern := git.TagPattern{}.WithPrefix(tt.prefix)
+			version, tag, ok := nextVersion(tagged(tt.tags...), pattern, tt.commits, tt.channel)
+			if tt.wantNone {
+				require.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			require.Equal(t, tt.wantTag, tag)
+			require.True(t, strings.HasSuffix(tag, version.String()))
+		})
+	}
+}
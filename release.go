@@ -0,0 +1,288 @@
+package main
+
+import (
+	"io"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/git"
+	"github.com/pkg/errors"
+)
+
+type releaseConfig struct {
+	FromRef    string
+	ToRef      string
+	TagPattern git.TagPattern
+	// SkipPreReleases makes the previous final release the start of a final release,
+	// so the changelog of 1.1.0 includes the changes of 1.1.0-rc.1 too.
+	SkipPreReleases bool
+	MergeCommits    git.MergeMode
+	// Paths limits the releases to the commits touching the given paths, to generate the changelog of a monorepo project.
+	Paths []string
+}
+
+func (cfg releaseConfig) logOptions() git.LogOptions {
+	return git.LogOptions{Merges: cfg.MergeCommits, Paths: cfg.Paths}
+}
+
+type release struct {
+	// Commits are ordered like git log, newest first.
+	Commits     []git.Commit
+	Tag         string
+	PreviousTag string
+	StartCommit git.Commit
+	EndCommit   git.Commit
+}
+
+// compareRefs returns the revisions of the start and the end of the release, preferring tags over commit hashes.
+func (r release) compareRefs() (string, string) {
+	from := r.PreviousTag
+	if from == "" {
+		from = r.StartCommit.Hash
+	}
+	to := r.Tag
+	if to == "" {
+		to = r.EndCommit.Hash
+	}
+	return from, to
+}
+
+func (r release) compareRange() string {
+	from, to := r.compareRefs()
+	if from == "" || to == "" {
+		return ""
+	}
+	return from + ".." + to
+}
+
+// releaseCommits collects the commits of the release.
+// If neither fromRef nor toRef is set, the release is the range between the last two tags,
+// or the commits from the first commit till HEAD if there are less than two tags.
+// If toRef is set, the release ends at toRef (inclusive), otherwise at HEAD.
+// If fromRef is set, the release starts after fromRef (exclusive), otherwise after the last tag preceding the end of the release.
+// The release contains the commits reachable from the end of the release but not from its start (git log start..end).
+func releaseCommits(dir string, cfg releaseConfig, taggedCommits []git.Commit) (release, error) {
+	fromRef, toRef := cfg.FromRef, cfg.ToRef
+	tags := commitTags(taggedCommits)
+
+	var err error
+	var startCommit, endCommit git.Commit
+	includeFirst := true
+	if fromRef == "" && toRef == "" {
+		startCommit, err = git.FirstCommit(dir)
+		if err != nil {
+			return release{}, errors.WithStack(err)
+		}
+
+		endCommit, err = git.LastCommit(dir)
+		if err != nil {
+			return release{}, errors.WithStack(err)
+		}
+
+		if len(taggedCommits) > 1 {
+			// collecting changelog between existing versions
+			endCommit = taggedCommits[len(taggedCommits)-1]
+			if previous, ok := previousRelease(taggedCommits[:len(taggedCommits)-1], endCommit, cfg); ok {
+				startCommit = previous
+				includeFirst = false
+			}
+		}
+	} else {
+		if toRef == "" {
+			toRef = "HEAD"
+		}
+
+		endCommit, err = git.RevisionCommit(dir, toRef)
+		if err != nil {
+			return release{}, errors.WithStack(err)
+		}
+
+		if fromRef != "" {
+			startCommit, err = git.RevisionCommit(dir, fromRef)
+			if err != nil {
+				return release{}, errors.WithStack(err)
+			}
+			includeFirst = false
+		} else if previous, ok, err := previousTaggedCommit(dir, taggedCommits, endCommit, tags[endCommit.Hash], cfg); err != nil {
+			return release{}, errors.WithStack(err)
+		} else if ok {
+			startCommit = previous
+			includeFirst = false
+		} else {
+			startCommit, err = git.FirstCommit(dir)
+			if err != nil {
+				return release{}, errors.WithStack(err)
+			}
+		}
+	}
+	if endCommit.Tag == "" {
+		endCommit.Tag = tags[endCommit.Hash]
+	}
+	if startCommit.Tag == "" {
+		startCommit.Tag = tags[startCommit.Hash]
+	}
+
+	return rangeRelease(dir, cfg.logOptions(), tags, startCommit, endCommit, includeFirst)
+}
+
+// releaseHistory collects every release: one release per tag and the unreleased commits after the last tag.
+// The releases are ordered from the newest, the unreleased commits come first if there are any.
+// If SkipPreReleases is set, pre-release tags are not listed and their commits belong to the following final release.
+// Tags of the same commit are listed as a single release of the highest tag.
+func releaseHistory(dir string, cfg releaseConfig, taggedCommits []git.Commit) ([]release, error) {
+	tags := commitTags(taggedCommits)
+	var err error
+
+	// the tags of the same commit (1.1.0-rc.1 and 1.1.0) are a single release of the highest tag
+	lastTagOfCommit := map[string]int{}
+	for i, taggedCommit := range taggedCommits {
+		lastTagOfCommit[taggedCommit.Hash] = i
+	}
+
+	var releaseTags []git.Commit
+	for i, taggedCommit := range taggedCommits {
+		if lastTagOfCommit[taggedCommit.Hash] != i || (cfg.SkipPreReleases && cfg.TagPattern.IsPreRelease(taggedCommit.Tag)) {
+			continue
+		}
+		releaseTags = append(releaseTags, taggedCommit)
+	}
+
+	var releases []release
+	for i, endCommit := range releaseTags {
+		startCommit := git.Commit{}
+		includeFirst := i == 0
+		if includeFirst {
+			startCommit, err = git.FirstCommit(dir)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+		} else {
+			startCommit = releaseTags[i-1]
+		}
+
+		r, err := rangeRelease(dir, cfg.logOptions(), tags, startCommit, endCommit, includeFirst)
+		if err != nil {
+			return nil, err
+		}
+		releases = append([]release{r}, releases...)
+	}
+
+	lastCommit, err := git.LastCommit(dir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if len(releaseTags) == 0 {
+		firstCommit, err := git.FirstCommit(dir)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		r, err := rangeRelease(dir, cfg.logOptions(), tags, firstCommit, lastCommit, true)
+		if err != nil {
+			return nil, err
+		}
+		return []release{r}, nil
+	}
+
+	unreleased, err := rangeRelease(dir, cfg.logOptions(), tags, releaseTags[len(releaseTags)-1], lastCommit, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(unreleased.Commits) > 0 {
+		releases = append([]release{unreleased}, releases...)
+	}
+
+	return releases, nil
+}
+
+// commitTags maps the hashes of the tagged commits to their tags.
+func commitTags(taggedCommits []git.Commit) map[string]string {
+	tags := map[string]string{}
+	for _, taggedCommit := range taggedCommits {
+		tags[taggedCommit.Hash] = taggedCommit.Tag
+	}
+	return tags
+}
+
+// rangeRelease collects the commits reachable from endCommit but not from startCommit, newest first.
+// If includeFirst is set, every commit reachable from endCommit is collected.
+func rangeRelease(dir string, opts git.LogOptions, tags map[string]string, startCommit, endCommit git.Commit, includeFirst bool) (release, error) {
+	fromRevision := ""
+	if !includeFirst {
+		fromRevision = startCommit.Hash
+	}
+
+	it, err := git.IterateCommits(dir, fromRevision, endCommit.Hash, opts)
+	if err != nil {
+		return release{}, errors.WithStack(err)
+	}
+	defer it.Close()
+
+	var releaseCommits []git.Commit
+	for {
+		commit, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return release{}, errors.WithStack(err)
+		}
+		commit.Tag = tags[commit.Hash]
+		releaseCommits = append(releaseCommits, commit)
+	}
+
+	r := release{
+		Commits:     releaseCommits,
+		Tag:         endCommit.Tag,
+		StartCommit: startCommit,
+		EndCommit:   endCommit,
+	}
+	if !includeFirst {
+		r.PreviousTag = startCommit.Tag
+	}
+
+	return r, nil
+}
+
+// previousRelease returns the last of the ordered tagged commits which can be the previous release of the given tagged commit.
+// The tags of the same commit (1.1.0-rc.1 and 1.1.0) are not previous releases, their release would be empty.
+func previousRelease(taggedCommits []git.Commit, endCommit git.Commit, cfg releaseConfig) (git.Commit, bool) {
+	for i := len(taggedCommits) - 1; i >= 0; i-- {
+		if taggedCommits[i].Hash != endCommit.Hash && !isSkippedRelease(taggedCommits[i].Tag, endCommit.Tag, cfg) {
+			return taggedCommits[i], true
+		}
+	}
+	return git.Commit{}, false
+}
+
+// previousTaggedCommit returns the last tagged commit which is an ancestor of the given commit.
+func previousTaggedCommit(dir string, taggedCommits []git.Commit, commit git.Commit, tag string, cfg releaseConfig) (git.Commit, bool, error) {
+	if tag != "" {
+		// only the tags preceding the release tag can be the previous release
+		for i, taggedCommit := range taggedCommits {
+			if taggedCommit.Tag == tag {
+				taggedCommits = taggedCommits[:i]
+				break
+			}
+		}
+	}
+
+	for i := len(taggedCommits) - 1; i >= 0; i-- {
+		if taggedCommits[i].Hash == commit.Hash || isSkippedRelease(taggedCommits[i].Tag, tag, cfg) {
+			continue
+		}
+
+		isAncestor, err := git.IsAncestor(dir, taggedCommits[i].Hash, commit.Hash)
+		if err != nil {
+			return git.Commit{}, false, err
+		}
+		if isAncestor {
+			return taggedCommits[i], true, nil
+		}
+	}
+	return git.Commit{}, false, nil
+}
+
+// isSkippedRelease reports whether the candidate tag is a pre-release to skip when looking for the previous release of the given tag.
+func isSkippedRelease(candidate, tag string, cfg releaseConfig) bool {
+	return cfg.SkipPreReleases && tag != "" && !cfg.TagPattern.IsPreRelease(tag) && cfg.TagPattern.IsPreRelease(candidate)
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/git"
+	"github.com/bitrise-steplib/steps-generate-changelog/internal/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func taggedCommits(t *testing.T, repo gittest.Repo, cfg releaseConfig) []git.Commit {
+	taggedCommits, err := git.TaggedCommits(repo.Dir, cfg.TagPattern)
+	require.NoError(t, err)
+	return taggedCommits
+}
+
+func releaseMessages(r release) []string {
+	var messages []string
+	for _, commit := range r.Commits {
+		messages = append(messages, commit.Message)
+	}
+	return messages
+}
+
+func Test_releaseCommits_sharedCommit(t *testing.T) {
+	repo := gittest.New(t)
+	repo.Commit("initial", "2020-01-01T00:00:00Z")
+	repo.Git("tag", "1.0.0")
+	repo.Commit("feat: login", "2020-01-02T00:00:00Z")
+	repo.Commit("fix: crash", "2020-01-03T00:00:00Z")
+	repo.Git("tag", "1.1.0-rc.1")
+	repo.Git("tag", "1.1.0")
+
+	for _, skipPreReleases := range []bool{false, true} {
+		cfg := releaseConfig{SkipPreReleases: skipPreReleases}
+		r, err := releaseCommits(repo.Dir, cfg, taggedCommits(t, repo, cfg))
+		require.NoError(t, err)
+		require.Equal(t, "1.1.0", r.Tag)
+		require.Equal(t, "1.0.0", r.PreviousTag)
+		require.Equal(t, []string{"fix: crash", "feat: login"}, releaseMessages(r))
+	}
+}
+
+func Test_previousRelease(t *testing.T) {
+	taggedCommits := []git.Commit{
+		{Hash: "1111", Tag: "1.0.0"},
+		{Hash: "2222", Tag: "1.1.0-rc.1"},
+		{Hash: "3333", Tag: "1.1.0-rc.2"},
+	}
+
+	previous, ok := previousRelease(taggedCommits, git.Commit{Hash: "3333", Tag: "1.1.0"}, releaseConfig{})
+	require.True(t, ok)
+	require.Equal(t, "1.1.0-rc.1", previous.Tag)
+
+	previous, ok = previousRelease(taggedCommits, git.Commit{Hash: "4444", Tag: "1.1.0"}, releaseConfig{SkipPreReleases: true})
+	require.True(t, ok)
+	require.Equal(t, "1.0.0", previous.Tag)
+
+	_, ok = previousRelease(taggedCommits[:1], git.Commit{Hash: "1111", Tag: "1.0.0-rc.1"}, releaseConfig{})
+	require.False(t, ok)
+}
+
+func Test_releaseHistory_sharedCommit(t *testing.T) {
+	repo := gittest.New(t)
+	repo.Commit("initial", "2020-01-01T00:00:00Z")
+	repo.Git("tag", "1.0.0")
+	repo.Commit("feat: login", "2020-01-02T00:00:00Z")
+	repo.Git("tag", "1.1.0-rc.1")
+	repo.Git("tag", "1.1.0")
+	repo.Commit("fix: crash", "2020-01-03T00:00:00Z")
+
+	releases, err := releaseHistory(repo.Dir, releaseConfig{}, taggedCommits(t, repo, releaseConfig{}))
+	require.NoError(t, err)
+	require.Len(t, releases, 3)
+
+	require.Equal(t, "", releases[0].Tag)
+	require.Equal(t, "1.1.0", releases[0].PreviousTag)
+	require.Equal(t, []string{"fix: crash"}, releaseMessages(releases[0]))
+
+	require.Equal(t, "1.1.0", releases[1].Tag)
+	require.Equal(t, "1.0.0", releases[1].PreviousTag)
+	require.Equal(t, []string{"feat: login"}, releaseMessages(releases[1]))
+
+	require.Equal(t, "1.0.0", releases[2].Tag)
+	require.Equal(t, []string{"initial"}, releaseMessages(releases[2]))
+}
+
+func Test_releaseCommits_refs(t *testing.T) {
+	repo := gittest.New(t)
+	repo.Commit("initial", "2020-01-01T00:00:00Z")
+	repo.Git("tag", "1.0.0")
+	featA := repo.Commit("feat: a", "2020-01-02T00:00:00Z")
+	repo.Commit("feat: b", "2020-01-03T00:00:00Z")
+	repo.Git("tag", "1.1.0")
+	repo.Commit("fix: c", "2020-01-04T00:00:00Z")
+	repo.Commit("fix: d", "2020-01-05T00:00:00Z")
+
+	repo.Git("checkout", "-q", "-b", "hotfix", "1.0.0")
+	repo.Commit("fix: hotfix", "2020-01-06T00:00:00Z")
+	repo.Git("checkout", "-q", "-")
+
+	tests := []struct {
+		name            string
+		fromRef         string
+		toRef           string
+		wantTag         string
+		wantPreviousTag string
+		wantMessages    []string
+	}{
+		{
+			name:            "tag range",
+			fromRef:         "1.0.0",
+			toRef:           "1.1.0",
+			wantTag:         "1.1.0",
+			wantPreviousTag: "1.0.0",
+			wantMessages:    []string{"feat: b", "feat: a"},
+		},
+		{
+			name:            "relative revision starts after the previous tag",
+			toRef:           "HEAD~1",
+			wantPreviousTag: "1.1.0",
+			wantMessages:    []string{"fix: c"},
+		},
+		{
+			name:         "commit hash till HEAD",
+			fromRef:      featA,
+			wantMessages: []string{"fix: d", "fix: c", "feat: b"},
+		},
+		{
+			name:            "branch starts after the tag it was branched from",
+			toRef:           "hotfix",
+			wantPreviousTag: "1.0.0",
+			wantMessages:    []string{"fix: hotfix"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := releaseConfig{FromRef: tt.fromRef, ToRef: tt.toRef}
+			r, err := releaseCommits(repo.Dir, cfg, taggedCommits(t, repo, cfg))
+			require.NoError(t, err)
+			require.Equal(t, tt.wantTag, r.Tag)
+			require.Equal(t, tt.wantPreviousTag, r.PreviousTag)
+			require.Equal(t, tt.wantMessages, releaseMessages(r))
+		})
+	}
+}
+
+func Test_releaseCommits_backdatedCommits(t *testing.T) {
+	repo := gittest.New(t)
+	repo.Commit("initial", "2020-01-10T00:00:00Z")
+	repo.Git("tag", "1.0.0")
+	repo.Commit("feat: backdated", "2019-01-01T00:00:00Z")
+	repo.Commit("fix: same time as the tag", "2020-01-10T00:00:00Z")
+	repo.Git("tag", "1.1.0")
+
+	r, err := releaseCommits(repo.Dir, releaseConfig{}, taggedCommits(t, repo, releaseConfig{}))
+	require.NoError(t, err)
+	require.Equal(t, "1.0.0", r.PreviousTag)
+	require.Equal(t, []string{"fix: same time as the tag", "feat: backdated"}, releaseMessages(r))
+}
+
+func Test_previousTaggedCommit(t *testing.T) {
+	repo := gittest.New(t)
+	repo.Commit("initial", "2020-01-01T00:00:00Z")
+	repo.Git("tag", "1.0.0")
+	repo.Commit("feat: login", "2020-01-02T00:00:00Z")
+	repo.Git("tag", "1.1.0-rc.1")
+
+	// a newer tag on a branch which is not merged
+	repo.Git("checkout", "-q", "-b", "side")
+	repo.Commit("feat: side", "2020-01-03T00:00:00Z")
+	repo.Git("tag", "1.2.0")
+	repo.Git("checkout", "-q", "-")
+
+	head := repo.Commit("fix: crash", "2020-01-04T00:00:00Z")
+	repo.Git("tag", "1.1.0")
+
+	tests := []struct {
+		name string
+		tag  string
+		cfg  releaseConfig
+		want string
+	}{
+		{name: "untagged commit", want: "1.1.0-rc.1"},
+		{name: "release tag", tag: "1.1.0", want: "1.1.0-rc.1"},
+		{name: "skip pre-releases", tag: "1.1.0", cfg: releaseConfig{SkipPreReleases: true}, want: "1.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous, ok, err := previousTaggedCommit(repo.Dir, taggedCommits(t, repo, tt.cfg), git.Commit{Hash: head}, tt.tag, tt.cfg)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, tt.want, previous.Tag)
+		})
+	}
+
+	// no tag precedes the first release
+	first, err := git.RevisionCommit(repo.Dir, "1.0.0")
+	require.NoError(t, err)
+	_, ok, err := previousTaggedCommit(repo.Dir, taggedCommits(t, repo, releaseConfig{}), first, "1.0.0", releaseConfig{})
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func Test_isSkippedRelease(t *testing.T) {
+	tagPattern, err := git.ParseTagPattern("")
+	require.NoError(t, err)
+	skip := releaseConfig{SkipPreReleases: true, TagPattern: tagPattern.WithPrefix("ios/")}
+
+	tests := []struct {
+		name      string
+		candidate string
+		tag       string
+		cfg       releaseConfig
+		want      bool
+	}{
+		{name: "pre-release before a final release", candidate: "ios/1.1.0-rc.1", tag: "ios/1.1.0", cfg: skip, want: true},
+		{name: "final release", candidate: "ios/1.0.0", tag: "ios/1.1.0", cfg: skip, want: false},
+		{name: "pre-release before a pre-release", candidate: "ios/1.1.0-rc.1", tag: "ios/1.1.0-rc.2", cfg: skip, want: false},
+		{name: "pre-release before unreleased commits", candidate: "ios/1.1.0-rc.1", cfg: skip, want: false},
+		{name: "pre-releases are not skipped", candidate: "ios/1.1.0-rc.1", tag: "ios/1.1.0", cfg: releaseConfig{TagPattern: skip.TagPattern}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, isSkippedRelease(tt.candidate, tt.tag, tt.cfg))
+		})
+	}
+}
+
+func Test_releaseCommits_monorepo(t *testing.T) {
+	repo := gittest.New(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(repo.Dir, "ios"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(repo.Dir, "android"), 0755))
+
+	commitFile := func(message, file, date string) {
+		require.NoError(t, os.WriteFile(filepath.Join(repo.Dir, file), []byte(message), 0644))
+		repo.Git("add", ".")
+		repo.Commit(message, date)
+	}
+	commitFile("feat: ios login", "ios/App.swift", "2020-01-01T00:00:00Z")
+	repo.Git("tag", "ios/v1.0.0")
+	commitFile("feat: android login", "android/App.kt", "2020-01-02T00:00:00Z")
+	repo.Git("tag", "android/v3.0.0")
+	commitFile("fix: ios crash", "ios/App.swift", "2020-01-03T00:00:00Z")
+	commitFile("fix: android crash", "android/App.kt", "2020-01-04T00:00:00Z")
+	repo.Git("tag", "android/v3.0.1")
+	commitFile("feat: ios dark mode", "ios/App.swift", "2020-01-05T00:00:00Z")
+	repo.Git("tag", "ios/v1.1.0")
+
+	tagPattern, err := git.ParseTagPattern("")
+	require.NoError(t, err)
+
+	ios := releaseConfig{TagPattern: tagPattern.WithPrefix("ios/"), Paths: []string{"ios"}}
+	r, err := releaseCommits(repo.Dir, ios, taggedCommits(t, repo, ios))
+	require.NoError(t, err)
+	require.Equal(t, "ios/v1.1.0", r.Tag)
+	require.Equal(t, "ios/v1.0.0", r.PreviousTag)
+	require.Equal(t, []string{"feat: ios dark mode", "fix: ios crash"}, releaseMessages(r))
+
+	android := releaseConfig{TagPattern: tagPattern.WithPrefix("android/"), Paths: []string{"android"}}
+	r, err = releaseCommits(repo.Dir, android, taggedCommits(t, repo, android))
+	require.NoError(t, err)
+	require.Equal(t, "android/v3.0.1", r.Tag)
+	require.Equal(t, "android/v3.0.0", r.PreviousTag)
+	require.Equal(t, []string{"fix: android crash"}, releaseMessages(r))
+
+	releases, err := releaseHistory(repo.Dir, ios, taggedCommits(t, repo, ios))
+	require.NoError(t, err)
+	require.Len(t, releases, 2)
+	require.Equal(t, "ios/v1.1.0", releases[0].Tag)
+	require.Equal(t, []string{"feat: ios dark mode", "fix: ios crash"}, releaseMessages(releases[0]))
+	require.Equal(t, "ios/v1.0.0", releases[1].Tag)
+	require.Equal(t, []string{"feat: ios login"}, releaseMessages(releases[1]))
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/exporter"
+	"github.com/bitrise-steplib/steps-generate-changelog/git"
+)
+
+const (
+	appStoreReleaseNotesEnvKey   = "BITRISE_CHANGELOG_APP_STORE"
+	googlePlayReleaseNotesEnvKey = "BITRISE_CHANGELOG_GOOGLE_PLAY"
+)
+
+const (
+	// appStoreMaxChars is the limit of the App Store Connect "What's New in This Version" text.
+	appStoreMaxChars   = exporter.FastlaneMaxChars
+	googlePlayMaxChars = exporter.WhatsNewMaxChars
+)
+
+// The limit policies decide what happens if the release notes are longer than the limit of the store.
+const (
+	truncateLimitPolicy = "truncate"
+	failLimitPolicy     = "fail"
+)
+
+// releaseNotesSection is a titled group of plain text release notes entries.
+type releaseNotesSection struct {
+	Title   string
+	Entries []string
+}
+
+// releaseNotesSections returns the sections of the release notes of the release:
+// the changelog sections with one entry per change, without commit hashes, links and bodies.
+func releaseNotesSections(r release, cfg sectionConfig) []releaseNotesSection {
+	var sections []releaseNotesSection
+	for _, section := range changelogSections(groupFixups(r.Commits), cfg) {
+		notesSection := releaseNotesSection{Title: section.Title}
+		for _, commit := range section.Commits {
+			notesSection.Entries = append(notesSection.Entries, releaseNotesEntry(commit))
+		}
+		sections = append(sections, notesSection)
+	}
+	return sections
+}
+
+func releaseNotesEntry(commit git.Commit) string {
+	switch {
+	case commit.Type != "" && commit.Scope != "":
+		return commit.Scope + ": " + commit.Description
+	case commit.Type != "":
+		return commit.Description
+	case commit.PullRequest != nil:
+		return commit.PullRequest.Title
+	}
+	return commit.Message
+}
+
+// renderReleaseNotes renders the release notes as plain text of at most maxChars characters (unicode code points).
+// If the release notes are longer, whole entries are dropped from the end, the sections left without entries are removed
+// and a "+N more changes" line is appended. It reports whether entries were dropped.
+func renderReleaseNotes(sections []releaseNotesSection, maxChars int) (string, bool) {
+	total := 0
+	for _, section := range sections {
+		total += len(section.Entries)
+	}
+
+	render := func(dropped int) string {
+		notes := renderReleaseNotesEntries(sections, total-dropped)
+		if dropped > 0 {
+			notes = strings.TrimPrefix(notes+"\n"+moreChanges(dropped), "\n")
+		}
+		return notes
+	}
+	fits := func(dropped int) bool {
+		return utf8.RuneCountInString(render(dropped)) <= maxChars
+	}
+
+	if fits(0) {
+		return render(0), false
+	}
+	// once the "+N more changes" line is added, the notes get shorter as more entries are dropped
+	if dropped := 1 + sort.Search(total, func(i int) bool { return fits(i + 1) }); dropped <= total {
+		return render(dropped), true
+	}
+
+	// not even the "+N more changes" line fits
+	return string([]rune(moreChanges(total))[:maxChars]), true
+}
+
+// renderReleaseNotesEntries renders the first kept entries of the sections.
+func renderReleaseNotesEntries(sections []releaseNotesSection, kept int) string {
+	var blocks []string
+	for _, section := range sections {
+		if kept == 0 {
+			break
+		}
+
+		entries := section.Entries
+		if len(entries) > kept {
+			entries = entries[:kept]
+		}
+		kept -= len(entries)
+
+		var lines []string
+		if section.Title != "" {
+			lines = append(lines, section.Title+":")
+		}
+		for _, entry := range entries {
+			lines = append(lines, "• "+entry)
+		}
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+func moreChanges(count int) string {
+	if count == 1 {
+		return "+1 more change"
+	}
+	return fmt.Sprintf("+%d more changes", count)
+}
+
+// limitedReleaseNotes renders the release notes of at most maxChars characters.
+// Entries are dropped from longer release notes by the truncate policy, and an error is returned by the fail policy.
+func limitedReleaseNotes(sections []releaseNotesSection, maxChars int, policy string) (string, error) {
+	notes, truncated := renderReleaseNotes(sections, maxChars)
+	if truncated && policy == failLimitPolicy {
+		full, _ := renderReleaseNotes(sections, math.MaxInt)
+		return "", fmt.Errorf("release notes are %d characters long, the limit is %d", utf8.RuneCountInString(full), maxChars)
+	}
+	return notes, nil
+}
+
+// releaseNotesOutputs returns the release notes of the stores as step outputs.
+func releaseNotesOutputs(sections []releaseNotesSection) []exporter.Output {
+	appStoreNotes, _ := renderReleaseNotes(sections, appStoreMaxChars)
+	googlePlayNotes, _ := renderReleaseNotes(sections, googlePlayMaxChars)
+	return []exporter.Output{
+		{Key: appStoreReleaseNotesEnvKey, Value: appStoreNotes},
+		{Key: googlePlayReleaseNotesEnvKey, Value: googlePlayNotes},
+	}
+}
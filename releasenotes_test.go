@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/git"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_releaseNotesSections(t *testing.T) {
+	r := release{Commits: []git.Commit{
+		{Hash: "4444", Date: time.Unix(4, 0), Message: "Update readme"},
+		{Hash: "3333", Date: time.Unix(3, 0), ConventionalCommit: git.ConventionalCommit{Type: "feat", Description: "dark mode"}},
+		{Hash: "2222", Date: time.Unix(2, 0), ConventionalCommit: git.ConventionalCommit{Type: "feat", Scope: "login", Description: "sign in with passkeys"}},
+		{Hash: "1111", Date: time.Unix(1, 0), ConventionalCommit: git.ConventionalCommit{Type: "fix", Description: "crash on start"}},
+	}}
+
+	sections := releaseNotesSections(r, sectionConfig{})
+	require.Equal(t, []releaseNotesSection{
+		{Title: "Features", Entries: []string{"dark mode", "login: sign in with passkeys"}},
+		{Title: "Bug Fixes", Entries: []string{"crash on start"}},
+		{Title: "Other Changes", Entries: []string{"Update readme"}},
+	}, sections)
+	require.Equal(t, "1111", r.Commits[3].Hash, "the commits of the release are not reordered")
+}
+
+func Test_renderReleaseNotes(t *testing.T) {
+	sections := []releaseNotesSection{
+		{Title: "Features", Entries: []string{"Dark mode", "Passkeys"}},
+		{Title: "Bug Fixes", Entries: []string{"Crash on start ✓"}},
+	}
+	full := "Features:\n• Dark mode\n• Passkeys\n\nBug Fixes:\n• Crash on start ✓"
+
+	notes, truncated := renderReleaseNotes(sections, 500)
+	require.Equal(t, full, notes)
+	require.False(t, truncated)
+
+	notes, truncated = renderReleaseNotes(sections, utf8.RuneCountInString(full))
+	require.Equal(t, full, notes)
+	require.False(t, truncated)
+
+	notes, truncated = renderReleaseNotes(sections, utf8.RuneCountInString(full)-1)
+	require.Equal(t, "Features:\n• Dark mode\n• Passkeys\n+1 more change", notes)
+	require.True(t, truncated)
+
+	notes, _ = renderReleaseNotes(sections, 37)
+	require.Equal(t, "Features:\n• Dark mode\n+2 more changes", notes)
+
+	notes, _ = renderReleaseNotes(sections, 20)
+	require.Equal(t, "+3 more changes", notes)
+
+	notes, _ = renderReleaseNotes(sections, 5)
+	require.Equal(t, "+3 mo", notes)
+
+	notes, truncated = renderReleaseNotes(nil, 500)
+	require.Empty(t, notes)
+	require.False(t, truncated)
+}
+
+func Test_limitedReleaseNotes(t *testing.T) {
+	sections := []releaseNotesSection{{Entries: []string{"Dark mode", "Passkeys"}}}
+
+	notes, err := limitedReleaseNotes(sections, 22, truncateLimitPolicy)
+	require.NoError(t, err)
+	require.Equal(t, "• Dark mode\n• Passkeys", notes)
+
+	notes, err = limitedReleaseNotes(sections, 21, truncateLimitPolicy)
+	require.NoError(t, err)
+	require.Equal(t, "+2 more changes", notes)
+
+	_, err = limitedReleaseNotes(sections, 21, failLimitPolicy)
+	require.EqualError(t, err, "release notes are 22 characters long, the limit is 21")
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/issues"
+)
+
+const (
+	markdownFormat = "markdown"
+	jsonFormat     = "json"
+	htmlFormat     = "html"
+	textFormat     = "text"
+)
+
+// formatTemplates are the default templates of an output format.
+// The release template renders the sections of a release, and it is embedded into the document template
+// (rendering a single release) and into the history template (rendering every release).
+type formatTemplates struct {
+	release  string
+	document string
+	history  string
+}
+
+const markdownReleaseTmplStr = `{{range $i, $section := .Sections}}{{if $i}}
+{{end}}{{if .Title}}### {{.Title}}
+{{end}}{{range .Commits}}* {{if .URL}}[{{firstChars .Hash 7}}]({{.URL}}){{else}}[{{firstChars .Hash 7}}]{{end}} {{if .Type}}{{if .Scope}}**{{escapeMarkdown .Scope}}:** {{end}}{{linkIssuesMarkdown .Description}}{{else if .PullRequest}}{{linkIssuesMarkdown .PullRequest.Title}}{{else}}{{linkIssuesMarkdown .Message}}{{end}}{{with .PullRequest}} ({{if .URL}}[#{{.Number}}]({{.URL}}){{else}}#{{.Number}}{{end}}){{end}}
+{{if includeBody}}{{with .BodyWithoutTrailers}}
+{{indent 2 (linkIssuesMarkdown .)}}
+{{end}}{{end}}{{end}}{{end}}{{if and listContributors .Contributors}}
+### Contributors
+{{range .Contributors}}* {{escapeMarkdown .Name}}
+{{end}}{{end}}{{if .CompareURL}}
+[Full diff]({{.CompareURL}})
+{{end}}`
+
+const markdownHistoryTmplStr = `# Changelog
+{{range .Releases}}
+## {{if .Tag}}[{{.Tag}}] - {{.Date.Format "2006-01-02"}}{{else}}[Unreleased]{{end}}
+{{template "release" .}}{{end}}`
+
+const textReleaseTmplStr = `{{range $i, $section := .Sections}}{{if $i}}
+{{end}}{{if .Title}}{{.Title}}:
+{{end}}{{range .Commits}}* [{{firstChars .Hash 7}}] {{if .Type}}{{if .Scope}}{{.Scope}}: {{end}}{{.Description}}{{else if .PullRequest}}{{.PullRequest.Title}}{{else}}{{.Message}}{{end}}{{with .PullRequest}} (#{{.Number}}){{end}}
+{{if includeBody}}{{with .BodyWithoutTrailers}}
+{{indent 2 .}}
+{{end}}{{end}}{{end}}{{end}}{{if and listContributors .Contributors}}
+Contributors:
+{{range .Contributors}}* {{.Name}}
+{{end}}{{end}}{{if .CompareURL}}
+Full diff: {{.CompareURL}}
+{{end}}`
+
+const textHistoryTmplStr = `{{range $i, $release := .Releases}}{{if $i}}
+{{end}}{{if .Tag}}{{.Tag}} - {{.Date.Format "2006-01-02"}}{{else}}Unreleased{{end}}
+{{template "release" .}}{{end}}`
+
+const htmlReleaseTmplStr = `{{range .Sections}}{{if .Title}}<h3>{{.Title}}</h3>
+{{end}}<ul>
+{{range .Commits}}<li>{{if .URL}}<a href="{{.URL}}"><code>{{firstChars .Hash 7}}</code></a>{{else}}<code>{{firstChars .Hash 7}}</code>{{end}} {{if .Type}}{{if .Scope}}<strong>{{.Scope}}:</strong> {{end}}{{linkIssuesHTML .Description}}{{else if .PullRequest}}{{linkIssuesHTML .PullRequest.Title}}{{else}}{{linkIssuesHTML .Message}}{{end}}{{with .PullRequest}} ({{if .URL}}<a href="{{.URL}}">#{{.Number}}</a>{{else}}#{{.Number}}{{end}}){{end}}{{if includeBody}}{{with .BodyWithoutTrailers}}
+<pre>{{linkIssuesHTML .}}</pre>{{end}}{{end}}</li>
+{{end}}</ul>
+{{end}}{{if and listContributors .Contributors}}<h3>Contributors</h3>
+<ul>
+{{range .Contributors}}<li>{{.Name}}</li>
+{{end}}</ul>
+{{end}}{{if .CompareURL}}<p><a href="{{.CompareURL}}">Full diff</a></p>
+{{end}}`
+
+const htmlDocumentTmplStr = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Changelog{{if .Tag}} {{.Tag}}{{end}}</title>
+</head>
+<body>
+<h1>Changelog</h1>
+<h2>{{if .Tag}}{{.Tag}} - {{.Date.Format "2006-01-02"}}{{else}}Unreleased{{end}}</h2>
+{{template "release" .}}</body>
+</html>
+`
+
+const htmlHistoryTmplStr = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Changelog</title>
+</head>
+<body>
+<h1>Changelog</h1>
+{{range .Releases}}<h2>{{if .Tag}}{{.Tag}} - {{.Date.Format "2006-01-02"}}{{else}}Unreleased{{end}}</h2>
+{{template "release" .}}{{end}}</body>
+</html>
+`
+
+var defaultTemplates = map[string]formatTemplates{
+	markdownFormat: {
+		release:  markdownReleaseTmplStr,
+		document: `{{template "release" .}}`,
+		history:  markdownHistoryTmplStr,
+	},
+	textFormat: {
+		release:  textReleaseTmplStr,
+		document: `{{template "release" .}}`,
+		history:  textHistoryTmplStr,
+	},
+	htmlFormat: {
+		release:  htmlReleaseTmplStr,
+		document: htmlDocumentTmplStr,
+		history:  htmlHistoryTmplStr,
+	},
+}
+
+func tmplFuncMap(cfg changelogConfig) map[string]interface{} {
+	return map[string]interface{}{
+		"firstChars": func(str string, length int) string {
+			if len(str) < length {
+				return str
+			}
+
+			return str[0:length]
+		},
+		"escapeMarkdown": escapeMarkdown,
+		"indent":         indent,
+		"includeBody": func() bool {
+			return cfg.IncludeBody
+		},
+		"listContributors": func() bool {
+			return cfg.ListContributors
+		},
+		"linkIssuesMarkdown": func(str string) string {
+			return issues.Replace(cfg.IssuePatterns, str, func(ref issues.Reference) string {
+				return fmt.Sprintf("[%s](%s)", escapeMarkdown(ref.Key), ref.URL)
+			}, escapeMarkdown)
+		},
+		"linkIssuesHTML": func(str string) htmltemplate.HTML {
+			return htmltemplate.HTML(issues.Replace(cfg.IssuePatterns, str, func(ref issues.Reference) string {
+				return fmt.Sprintf(`<a href="%s">%s</a>`, htmltemplate.HTMLEscapeString(ref.URL), htmltemplate.HTMLEscapeString(ref.Key))
+			}, htmltemplate.HTMLEscapeString))
+		},
+	}
+}
+
+// template parse errors are formatted as: template: <name>:<line>: <description>
+var tmplErrLineRegexp = regexp.MustCompile(`^template: [^:]+:(\d+):`)
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	`*`, `\*`,
+	`_`, `\_`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`<`, `\<`,
+	`>`, `\>`,
+)
+
+// escapeMarkdown escapes the characters which would change the formatting of a Markdown text.
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// indent indents the non-empty lines of the text with the given number of spaces.
+func indent(spaces int, text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = strings.Repeat(" ", spaces) + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+type executableTemplate interface {
+	Execute(wr io.Writer, data interface{}) error
+}
+
+// renderChangelog renders the changelog of a single release in the configured format.
+// If no template is configured, the default template of the format is used, otherwise the template renders the whole output.
+// The JSON format does not use templates.
+func renderChangelog(chlog changelog, cfg changelogConfig) (string, error) {
+	if cfg.Format == jsonFormat {
+		return renderJSON(chlog)
+	}
+
+	mainTmplStr, releaseTmplStr := cfg.Template, ""
+	if cfg.Template == "" {
+		mainTmplStr, releaseTmplStr = defaultTemplates[cfg.Format].document, defaultTemplates[cfg.Format].release
+	}
+
+	return renderTemplate(chlog, cfg, mainTmplStr, releaseTmplStr)
+}
+
+// renderHistory renders the changelog of every release in the configured format.
+// If no template is configured, the default template of the format is used, otherwise the template renders each release.
+// The JSON format does not use templates.
+func renderHistory(h history, cfg changelogConfig) (string, error) {
+	if cfg.Format == jsonFormat {
+		return renderJSON(h)
+	}
+
+	releaseTmplStr := cfg.Template
+	if releaseTmplStr == "" {
+		releaseTmplStr = defaultTemplates[cfg.Format].release
+	}
+
+	return renderTemplate(h, cfg, defaultTemplates[cfg.Format].history, releaseTmplStr)
+}
+
+func renderJSON(data interface{}) (string, error) {
+	b, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b) + "\n", nil
+}
+
+func renderTemplate(data interface{}, cfg changelogConfig, mainTmplStr, releaseTmplStr string) (string, error) {
+	tmpl, err := parseChangelogTemplate(cfg, mainTmplStr, releaseTmplStr)
+	if err != nil {
+		return "", err
+	}
+
+	var buff bytes.Buffer
+	if err := tmpl.Execute(&buff, data); err != nil {
+		return "", err
+	}
+
+	return buff.String(), nil
+}
+
+// parseChangelogTemplate parses the main template and, if given, the release template which can be included
+// into the main template with {{template "release" .}}.
+func parseChangelogTemplate(cfg changelogConfig, mainTmplStr, releaseTmplStr string) (executableTemplate, error) {
+	var tmpl executableTemplate
+	var err error
+	if cfg.Format == htmlFormat {
+		// html/template escapes the commit messages according to the HTML context
+		tmpl, err = parseHTMLTemplate(mainTmplStr, releaseTmplStr, tmplFuncMap(cfg))
+	} else {
+		tmpl, err = parseTextTemplate(mainTmplStr, releaseTmplStr, tmplFuncMap(cfg))
+	}
+	if err != nil {
+		if match := tmplErrLineRegexp.FindStringSubmatch(err.Error()); len(match) == 2 {
+			if line, convErr := strconv.Atoi(match[1]); convErr == nil {
+				return nil, fmt.Errorf("invalid changelog template at line %d: %s", line, err)
+			}
+		}
+		return nil, fmt.Errorf("invalid changelog template: %s", err)
+	}
+	return tmpl, nil
+}
+
+func parseTextTemplate(mainTmplStr, releaseTmplStr string, funcs map[string]interface{}) (*texttemplate.Template, error) {
+	tmpl, err := texttemplate.New("changelog_content").Funcs(funcs).Parse(mainTmplStr)
+	if err != nil {
+		return nil, err
+	}
+	if releaseTmplStr != "" {
+		if _, err := tmpl.New("release").Parse(releaseTmplStr); err != nil {
+			return nil, err
+		}
+	}
+	return tmpl, nil
+}
+
+func parseHTMLTemplate(mainTmplStr, releaseTmplStr string, funcs map[string]interface{}) (*htmltemplate.Template, error) {
+	tmpl, err := htmltemplate.New("changelog_content").Funcs(funcs).Parse(mainTmplStr)
+	if err != nil {
+		return nil, err
+	}
+	if releaseTmplStr != "" {
+		if _, err := tmpl.New("release").Parse(releaseTmplStr); err != nil {
+			return nil, err
+		}
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-generate-changelog/git"
+)
+
+const (
+	breakingChangesSectionKey = "breaking"
+	otherChangesSectionKey    = "other"
+)
+
+// defaultSectionOrder is the order of the changelog sections, keyed by the Conventional Commits type.
+var defaultSectionOrder = []string{
+	breakingChangesSectionKey,
+	"feat",
+	"fix",
+	"perf",
+	"revert",
+	"refactor",
+	"docs",
+	"style",
+	"test",
+	"build",
+	"ci",
+	"chore",
+	otherChangesSectionKey,
+}
+
+var defaultSectionTitles = map[string]string{
+	breakingChangesSectionKey: "Breaking Changes",
+	"feat":                    "Features",
+	"fix":                     "Bug Fixes",
+	"perf":                    "Performance Improvements",
+	"revert":                  "Reverts",
+	"refactor":                "Code Refactoring",
+	"docs":                    "Documentation",
+	"style":                   "Styles",
+	"test":                    "Tests",
+	"build":                   "Build System",
+	"ci":                      "Continuous Integration",
+	"chore":                   "Chores",
+	otherChangesSectionKey:    "Other Changes",
+}
+
+// Section is a titled group of changelog commits.
+type Section struct {
+	Type    string       `json:"type,omitempty"`
+	Title   string       `json:"title,omitempty"`
+	Commits []git.Commit `json:"commits"`
+}
+
+type sectionConfig struct {
+	Titles      map[string]string
+	HiddenTypes map[string]bool
+}
+
+func newSectionConfig(titleLines, hiddenTypes []string) (sectionConfig, error) {
+	cfg := sectionConfig{
+		Titles:      map[string]string{},
+		HiddenTypes: map[string]bool{},
+	}
+	for _, line := range titleLines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		split := strings.SplitN(line, "=", 2)
+		if len(split) != 2 || strings.TrimSpace(split[0]) == "" {
+			return sectionConfig{}, fmt.Errorf("invalid section title (%s), expected format: <type>=<title>", line)
+		}
+		cfg.Titles[strings.ToLower(strings.TrimSpace(split[0]))] = strings.TrimSpace(split[1])
+	}
+
+	for _, t := range hiddenTypes {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" {
+			cfg.HiddenTypes[t] = true
+		}
+	}
+
+	return cfg, nil
+}
+
+func (c sectionConfig) title(key string) string {
+	if title, ok := c.Titles[key]; ok {
+		return title
+	}
+	if title, ok := defaultSectionTitles[key]; ok {
+		return title
+	}
+	return key
+}
+
+// changelogSections groups the commits by their Conventional Commits type.
+// If none of the commits follow the Conventional Commits specification,
+// a single untitled section is returned with all the commits.
+func changelogSections(commits []git.Commit, cfg sectionConfig) []Section {
+	hasConventional := false
+	for _, commit := range commits {
+		if commit.Type != "" {
+			hasConventional = true
+			break
+		}
+	}
+	if !hasConventional {
+		if len(commits) == 0 || cfg.HiddenTypes[otherChangesSectionKey] {
+			return nil
+		}
+		return []Section{{Commits: commits}}
+	}
+
+	commitsByKey := map[string][]git.Commit{}
+	var customKeys []string
+	for _, commit := range commits {
+		key := commit.Type
+		switch {
+		case commit.Breaking:
+			key = breakingChangesSectionKey
+		case key == "":
+			key = otherChangesSectionKey
+		}
+		if key != breakingChangesSectionKey && cfg.HiddenTypes[key] {
+			continue
+		}
+
+		if _, ok := commitsByKey[key]; !ok && !contains(defaultSectionOrder, key) {
+			customKeys = append(customKeys, key)
+		}
+		commitsByKey[key] = append(commitsByKey[key], commit)
+	}
+
+	// types without a predefined position are listed before the other changes
+	order := append(append([]string{}, defaultSectionOrder[:len(defaultSectionOrder)-1]...), customKeys...)
+	order = append(order, otherChangesSectionKey)
+
+	var sections []Section
+	for _, key := range order {
+		if len(commitsByKey[key]) == 0 {
+			continue
+		}
+		sections = append(sections, Section{
+			Type:    key,
+			Title:   cfg.title(key),
+			Commits: commitsByKey[key],
+		})
+	}
+	return sections
+}
+
+func contains(list []string, item string) bool {
+	for _, i := range list {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
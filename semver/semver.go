@@ -0,0 +1,182 @@
+package semver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// https://semver.org/#is-there-a-suggested-regular-expression-regex-to-check-a-semver-string
+// extended with an optional "v" prefix.
+var versionRegexp = regexp.MustCompile(`^[vV]?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// Version is a semantic version (https://semver.org).
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	PreRelease []string
+	Build      string
+}
+
+// Parse parses a semantic version, optionally prefixed with "v" (v1.2.3-rc.1+build.5).
+func Parse(s string) (Version, error) {
+	match := versionRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return Version{}, fmt.Errorf("invalid semantic version: %s", s)
+	}
+
+	var numbers [3]uint64
+	for i := range numbers {
+		n, err := strconv.ParseUint(match[i+1], 10, 64)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid semantic version: %s: %s", s, err)
+		}
+		numbers[i] = n
+	}
+
+	v := Version{
+		Major: numbers[0],
+		Minor: numbers[1],
+		Patch: numbers[2],
+		Build: match[5],
+	}
+	if match[4] != "" {
+		v.PreRelease = strings.Split(match[4], ".")
+	}
+	return v, nil
+}
+
+// IsPreRelease reports whether the version has pre-release identifiers (1.0.0-rc.1).
+func (v Version) IsPreRelease() bool {
+	return len(v.PreRelease) > 0
+}
+
+// String returns the version without the "v" prefix.
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.IsPreRelease() {
+		s += "-" + strings.Join(v.PreRelease, ".")
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Bump is the kind of change of a release, by the rules of semantic versioning.
+type Bump int
+
+// Bumps ...
+const (
+	NoBump Bump = iota
+	PatchBump
+	MinorBump
+	MajorBump
+)
+
+// String returns the name of the bump (major, minor, patch or none).
+func (b Bump) String() string {
+	switch b {
+	case MajorBump:
+		return "major"
+	case MinorBump:
+		return "minor"
+	case PatchBump:
+		return "patch"
+	}
+	return "none"
+}
+
+// Bump returns the version following v by the given kind of change, without pre-release identifiers and build metadata.
+func (v Version) Bump(b Bump) Version {
+	switch b {
+	case MajorBump:
+		return Version{Major: v.Major + 1}
+	case MinorBump:
+		return Version{Major: v.Major, Minor: v.Minor + 1}
+	case PatchBump:
+		return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+	}
+	return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+}
+
+// NextPreRelease returns the next pre-release of v in the given channel (1.2.0-rc.3):
+// the number of the pre-release follows the highest existing pre-release of v in the channel, or is 1 if there is none.
+func (v Version) NextPreRelease(channel string, existing []Version) Version {
+	core := v.Bump(NoBump)
+
+	var number uint64
+	for _, e := range existing {
+		if e.Bump(NoBump).Compare(core) != 0 || len(e.PreRelease) != 2 || e.PreRelease[0] != channel {
+			continue
+		}
+		if n, err := strconv.ParseUint(e.PreRelease[1], 10, 64); err == nil && n > number {
+			number = n
+		}
+	}
+
+	core.PreRelease = []string{channel, strconv.FormatUint(number+1, 10)}
+	return core
+}
+
+// Compare returns -1, 0 or 1 if v has lower, equal or higher precedence than other.
+// Build metadata is ignored, as defined by the specification.
+func (v Version) Compare(other Version) int {
+	if c := compareUint(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+
+	// a version without pre-release has higher precedence than its pre-releases
+	switch {
+	case !v.IsPreRelease() && !other.IsPreRelease():
+		return 0
+	case !v.IsPreRelease():
+		return 1
+	case !other.IsPreRelease():
+		return -1
+	}
+
+	for i := 0; i < len(v.PreRelease) && i < len(other.PreRelease); i++ {
+		if c := compareIdentifier(v.PreRelease[i], other.PreRelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(v.PreRelease)), uint64(len(other.PreRelease)))
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareIdentifier compares pre-release identifiers:
+// numeric identifiers are compared numerically and have lower precedence than alphanumeric ones,
+// which are compared lexically.
+func compareIdentifier(a, b string) int {
+	aNum, aErr := strconv.ParseUint(a, 10, 64)
+	bNum, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareUint(aNum, bNum)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
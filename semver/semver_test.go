@@ -0,0 +1,93 @@
+package semver
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	v, err := Parse("v1.2.3-rc.1+build.5")
+	require.NoError(t, err)
+	require.Equal(t, Version{Major: 1, Minor: 2, Patch: 3, PreRelease: []string{"rc", "1"}, Build: "build.5"}, v)
+	require.True(t, v.IsPreRelease())
+	require.Equal(t, "1.2.3-rc.1+build.5", v.String())
+
+	v, err = Parse("10.0.1")
+	require.NoError(t, err)
+	require.Equal(t, Version{Major: 10, Minor: 0, Patch: 1}, v)
+	require.False(t, v.IsPreRelease())
+
+	for _, invalid := range []string{"", "1.2", "build-123", "android-1.2.3", "01.2.3", "1.2.3-", "1.2.3-01"} {
+		_, err := Parse(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestVersion_Compare(t *testing.T) {
+	// ordered by precedence, see: https://semver.org/#spec-item-11
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"v2.0.0",
+		"10.0.0",
+	}
+
+	var versions []Version
+	for i := len(ordered) - 1; i >= 0; i-- {
+		v, err := Parse(ordered[i])
+		require.NoError(t, err)
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Compare(versions[j]) < 0
+	})
+
+	var sorted []string
+	for _, v := range versions {
+		sorted = append(sorted, v.String())
+	}
+	require.Equal(t, []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2", "1.0.0-beta.11",
+		"1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0", "10.0.0",
+	}, sorted)
+
+	a, _ := Parse("1.0.0+build.1")
+	b, _ := Parse("1.0.0+build.2")
+	require.Equal(t, 0, a.Compare(b))
+}
+
+func TestVersion_Bump(t *testing.T) {
+	v, err := Parse("1.2.3-rc.1+build.5")
+	require.NoError(t, err)
+
+	require.Equal(t, "2.0.0", v.Bump(MajorBump).String())
+	require.Equal(t, "1.3.0", v.Bump(MinorBump).String())
+	require.Equal(t, "1.2.4", v.Bump(PatchBump).String())
+	require.Equal(t, "1.2.3", v.Bump(NoBump).String())
+	require.Equal(t, "minor", MinorBump.String())
+}
+
+func TestVersion_NextPreRelease(t *testing.T) {
+	var existing []Version
+	for _, s := range []string{"1.2.0-rc.1", "1.2.0-rc.2", "1.2.0-beta.5", "1.1.0-rc.7", "1.2.0-rc.x", "1.2.0"} {
+		v, err := Parse(s)
+		require.NoError(t, err)
+		existing = append(existing, v)
+	}
+
+	v := Version{Major: 1, Minor: 2}
+	require.Equal(t, "1.2.0-rc.3", v.NextPreRelease("rc", existing).String())
+	require.Equal(t, "1.2.0-beta.6", v.NextPreRelease("beta", existing).String())
+	require.Equal(t, "1.2.0-alpha.1", v.NextPreRelease("alpha", existing).String())
+	require.Equal(t, "1.3.0-rc.1", Version{Major: 1, Minor: 3}.NextPreRelease("rc", existing).String())
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// defaultTruncationFooter is used if the truncation footer input is not set, it is the default of the input in step.yml.
+const defaultTruncationFooter = "...and {{.Count}} more commits{{with .Filename}}, see {{.}}{{end}}"
+
+// truncationFooterData is the template context of the truncation footer.
+type truncationFooterData struct {
+	// Count is the number of the commit entries left out.
+	Count int
+	// Filename is the name of the changelog file, which contains the whole changelog.
+	// It is empty if the changelog file is not written (the file exporter is not selected).
+	Filename string
+}
+
+func parseTruncationFooter(footer string) (*template.Template, error) {
+	if footer == "" {
+		footer = defaultTruncationFooter
+	}
+	return template.New("truncation_footer").Option("missingkey=error").Parse(footer)
+}
+
+// truncateChangelog shortens the changelog to at most maxBytes bytes and appends the footer.
+// The size of the changelog is measured by size, which is at least the length of the changelog (the escaped changelog).
+// The changelog is cut at entry boundaries (list items with their indented bodies), sections left without entries
+// are removed and the enclosing HTML tags are closed. If not even the first entry fits,
+// the changelog is cut at the last UTF-8 character boundary which fits.
+// The footer refers to the changelog file by the base name of filepth, the name is empty if no file is written.
+func truncateChangelog(changelog string, maxBytes int, size func(string) int, footer *template.Template, filepth string) (string, error) {
+	filename := ""
+	if filepth != "" {
+		filename = filepath.Base(filepth)
+	}
+
+	lines := strings.SplitAfter(changelog, "\n")
+	entries, boundaries := classifyChangelogLines(lines)
+
+	totalEntries := countTrue(entries)
+	offset := len(changelog)
+	for kept := len(lines) - 1; kept > 0; kept-- {
+		offset -= len(lines[kept])
+		if offset > maxBytes || !boundaries[kept] {
+			continue
+		}
+
+		keptLines := trimOpenSections(lines[:kept])
+		keptEntries := countTrue(entries[:len(keptLines)])
+		if keptEntries == 0 {
+			break
+		}
+
+		truncated, err := finishTruncatedChangelog(strings.Join(keptLines, ""), footer, truncationFooterData{
+			Count:    totalEntries - keptEntries,
+			Filename: filename,
+		})
+		if err != nil {
+			return "", err
+		}
+		if size(truncated) <= maxBytes {
+			return truncated, nil
+		}
+	}
+
+	footerStr, err := renderTruncationFooter(footer, truncationFooterData{Count: totalEntries, Filename: filename})
+	if err != nil {
+		return "", err
+	}
+	footerStr = "\n" + footerStr
+
+	if size(footerStr) > maxBytes {
+		return "", fmt.Errorf("the truncation footer (%d bytes) does not fit into the limit (%d bytes)", size(footerStr), maxBytes)
+	}
+	// the longest prefix which fits together with the footer
+	maxCut := len(changelog)
+	if maxCut > maxBytes {
+		maxCut = maxBytes
+	}
+	cut := sort.Search(maxCut+1, func(cut int) bool {
+		return size(changelog[:cut]+footerStr) > maxBytes
+	}) - 1
+	for cut > 0 && cut < len(changelog) && !utf8.RuneStart(changelog[cut]) {
+		cut--
+	}
+	return changelog[:cut] + footerStr, nil
+}
+
+// classifyChangelogLines reports which lines start a commit entry, and before which lines the changelog can be cut:
+// the lines starting an entry or a section.
+// The contributors listed by the default templates (### Contributors, Contributors:, <h3>Contributors</h3>)
+// are not commit entries, and the list is either kept or left out as a whole.
+func classifyChangelogLines(lines []string) ([]bool, []bool) {
+	entries := make([]bool, len(lines))
+	boundaries := make([]bool, len(lines))
+	contributors := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case isChangelogEntry(line):
+			entries[i] = !contributors
+			boundaries[i] = !contributors
+		case isChangelogSection(line):
+			contributors = strings.Contains(line, "Contributors")
+			boundaries[i] = true
+		case contributors:
+			// the list of the contributors ends at an empty line or at the end of the HTML list
+			contributors = trimmed != "" && trimmed != "</ul>"
+		}
+	}
+	return entries, boundaries
+}
+
+func countTrue(values []bool) int {
+	count := 0
+	for _, value := range values {
+		if value {
+			count++
+		}
+	}
+	return count
+}
+
+// isChangelogEntry reports whether the line starts an entry of the default templates.
+func isChangelogEntry(line string) bool {
+	return strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "<li>")
+}
+
+// isChangelogSection reports whether the line starts a section (# Title, <h3>Title</h3>, Title:).
+func isChangelogSection(line string) bool {
+	if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "<h") {
+		return true
+	}
+	return !isIndented(line) && strings.HasSuffix(strings.TrimSpace(line), ":")
+}
+
+func isIndented(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
+
+// trimOpenSections removes the trailing empty lines and the section headers left without entries.
+func trimOpenSections(lines []string) []string {
+	for len(lines) > 0 {
+		last := lines[len(lines)-1]
+		trimmed := strings.TrimSpace(last)
+		if trimmed != "" && (isChangelogEntry(last) || isIndented(last) ||
+			strings.HasPrefix(trimmed, "</") || strings.HasSuffix(trimmed, "</li>")) {
+			break
+		}
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// finishTruncatedChangelog appends the footer, and closes the HTML tags left open around it.
+func finishTruncatedChangelog(changelog string, footer *template.Template, data truncationFooterData) (string, error) {
+	footerStr, err := renderTruncationFooter(footer, data)
+	if err != nil {
+		return "", err
+	}
+
+	changelog = strings.TrimSuffix(changelog, "\n") + "\n"
+	if !strings.HasPrefix(strings.TrimSpace(changelog), "<") {
+		return changelog + footerStr, nil
+	}
+
+	closeTag := func(tag string) {
+		open := strings.Count(changelog, "<"+tag+">")
+		for closed := strings.Count(changelog, "</"+tag+">"); closed < open; closed++ {
+			changelog += "</" + tag + ">\n"
+		}
+	}
+	closeTag("ul")
+	changelog += "<p>" + template.HTMLEscapeString(footerStr) + "</p>\n"
+	closeTag("body")
+	closeTag("html")
+	return strings.TrimSuffix(changelog, "\n"), nil
+}
+
+func renderTruncationFooter(footer *template.Template, data truncationFooterData) (string, error) {
+	var b bytes.Buffer
+	if err := footer.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("unable to render the truncation footer: %s", err)
+	}
+	return b.String(), nil
+}
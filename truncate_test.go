@@ -0,0 +1,141 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_truncateChangelog(t *testing.T) {
+	footer, err := parseTruncationFooter("...and {{.Count}} more commits, see {{.Filename}}")
+	require.NoError(t, err)
+	size := func(s string) int { return len(s) }
+
+	t.Run("markdown", func(t *testing.T) {
+		changelog := "### Features\n" +
+			"* [1111111] dark mode\n" +
+			"  with a body\n" +
+			"* [2222222] passkeys\n" +
+			"\n" +
+			"### Bug Fixes\n" +
+			"* [3333333] crash on start\n" +
+			"* [4444444] typo\n"
+
+		truncated, err := truncateChangelog(changelog, 110, size, footer, "/deploy/CHANGELOG.md")
+		require.NoError(t, err)
+		require.Equal(t, "### Features\n"+
+			"* [1111111] dark mode\n"+
+			"  with a body\n"+
+			"* [2222222] passkeys\n"+
+			"...and 2 more commits, see CHANGELOG.md", truncated)
+
+		truncated, err = truncateChangelog(changelog, 151, size, footer, "/deploy/CHANGELOG.md")
+		require.NoError(t, err)
+		require.Equal(t, "### Features\n"+
+			"* [1111111] dark mode\n"+
+			"  with a body\n"+
+			"* [2222222] passkeys\n"+
+			"\n"+
+			"### Bug Fixes\n"+
+			"* [3333333] crash on start\n"+
+			"...and 1 more commits, see CHANGELOG.md", truncated)
+		require.Len(t, truncated, 151)
+	})
+
+	t.Run("contributors are not commits", func(t *testing.T) {
+		changelog := "* [1111111] dark mode\n" +
+			"* [2222222] passkeys\n" +
+			"* [3333333] crash on start\n" +
+			"\n" +
+			"### Contributors\n" +
+			"* Alice\n" +
+			"* Bob\n" +
+			"* Carol\n" +
+			"* Dave\n"
+
+		truncated, err := truncateChangelog(changelog, 90, size, footer, "CHANGELOG.md")
+		require.NoError(t, err)
+		require.Equal(t, "* [1111111] dark mode\n"+
+			"* [2222222] passkeys\n"+
+			"...and 1 more commits, see CHANGELOG.md", truncated)
+
+		truncated, err = truncateChangelog(changelog, len(changelog)-1, size, footer, "CHANGELOG.md")
+		require.NoError(t, err)
+		require.Equal(t, "* [1111111] dark mode\n"+
+			"* [2222222] passkeys\n"+
+			"* [3333333] crash on start\n"+
+			"...and 0 more commits, see CHANGELOG.md", truncated)
+	})
+
+	t.Run("html", func(t *testing.T) {
+		changelog := "<h3>Features</h3>\n" +
+			"<ul>\n" +
+			"<li><code>1111111</code> dark mode</li>\n" +
+			"<li><code>2222222</code> passkeys</li>\n" +
+			"</ul>\n"
+
+		truncated, err := truncateChangelog(changelog, 120, size, footer, "CHANGELOG.html")
+		require.NoError(t, err)
+		require.Equal(t, "<h3>Features</h3>\n"+
+			"<ul>\n"+
+			"<li><code>1111111</code> dark mode</li>\n"+
+			"</ul>\n"+
+			"<p>...and 1 more commits, see CHANGELOG.html</p>", truncated)
+	})
+
+	t.Run("no entry fits", func(t *testing.T) {
+		changelog := strings.Repeat("ű", 100)
+
+		truncated, err := truncateChangelog(changelog, 51, size, footer, "CHANGELOG.md")
+		require.NoError(t, err)
+		require.True(t, utf8.ValidString(truncated))
+		require.LessOrEqual(t, len(truncated), 51)
+		require.True(t, strings.HasSuffix(truncated, "\n...and 0 more commits, see CHANGELOG.md"))
+	})
+
+	t.Run("escaped size", func(t *testing.T) {
+		changelog := "* [1111111] dark mode\n" +
+			"* [2222222] passkeys\n" +
+			"* [3333333] crash on start\n"
+		// like the GitLab dotenv line: KEY=value with the new lines escaped
+		escapedSize := func(s string) int { return len("CHANGELOG=" + strings.ReplaceAll(s, "\n", `\n`) + "\n") }
+
+		// the two entries with the footer would fit into 90 bytes unescaped
+		truncated, err := truncateChangelog(changelog, 90, escapedSize, footer, "CHANGELOG.md")
+		require.NoError(t, err)
+		require.Equal(t, "* [1111111] dark mode\n"+
+			"...and 2 more commits, see CHANGELOG.md", truncated)
+		require.LessOrEqual(t, escapedSize(truncated), 90)
+
+		truncated, err = truncateChangelog(strings.Repeat("\n", 100), 80, escapedSize, footer, "CHANGELOG.md")
+		require.NoError(t, err)
+		require.LessOrEqual(t, escapedSize(truncated), 80)
+		require.Greater(t, escapedSize("\n"+truncated), 80)
+	})
+
+	t.Run("no changelog file", func(t *testing.T) {
+		defaultFooter, err := parseTruncationFooter("")
+		require.NoError(t, err)
+		changelog := "* [1111111] dark mode\n* [2222222] passkeys\n"
+
+		truncated, err := truncateChangelog(changelog, 50, size, defaultFooter, "")
+		require.NoError(t, err)
+		require.Equal(t, "* [1111111] dark mode\n...and 1 more commits", truncated)
+
+		truncated, err = truncateChangelog(changelog, 70, size, defaultFooter, "/deploy/CHANGELOG.md")
+		require.NoError(t, err)
+		require.Equal(t, "* [1111111] dark mode\n...and 1 more commits, see CHANGELOG.md", truncated)
+	})
+
+	t.Run("footer does not fit", func(t *testing.T) {
+		_, err := truncateChangelog(strings.Repeat("a", 100), 10, size, footer, "CHANGELOG.md")
+		require.Error(t, err)
+	})
+}
+
+func Test_parseTruncationFooter(t *testing.T) {
+	_, err := parseTruncationFooter("{{.Count")
+	require.Error(t, err)
+}